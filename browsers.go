@@ -0,0 +1,346 @@
+package fuzzyHelpers
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	acceptHTML         = "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,*/*;q=0.8"
+	acceptChromiumHTML = "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8,application/signed-exchange;v=b3;q=0.7"
+	acceptSafariHTML   = "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"
+)
+
+var (
+	chromiumPseudoOrder = []string{":method", ":authority", ":scheme", ":path"}
+	firefoxPseudoOrder  = []string{":method", ":path", ":authority", ":scheme"}
+	safariPseudoOrder   = []string{":method", ":scheme", ":path", ":authority"}
+)
+
+func init() {
+	mustRegister(&Profile{
+		Name: "chrome",
+		Template: []HeaderField{
+			{"Connection", "keep-alive"},
+			{"Cache-Control", "max-age=0"},
+			{"sec-ch-ua", ""},
+			{"sec-ch-ua-arch", ""},
+			{"sec-ch-ua-bitness", ""},
+			{"sec-ch-ua-full-version", ""},
+			{"sec-ch-ua-full-version-list", ""},
+			{"sec-ch-ua-mobile", "?0"},
+			{"sec-ch-ua-model", ""},
+			{"sec-ch-ua-platform", ""},
+			{"sec-ch-ua-platform-version", ""},
+			{"sec-ch-ua-wow64", ""},
+			{"Upgrade-Insecure-Requests", "1"},
+			{"Origin", ""},
+			{"User-Agent", ""},
+			{"Accept", acceptChromiumHTML},
+			{"Sec-Fetch-Site", "none"},
+			{"Sec-Fetch-Mode", "navigate"},
+			{"Sec-Fetch-User", "?1"},
+			{"Sec-Fetch-Dest", "document"},
+			{"Referer", ""},
+			{"Accept-Encoding", acceptEncoding},
+			{"Accept-Language", "en-US,en;q=0.9"},
+		},
+		UserAgents:        chromeUserAgents,
+		ClientHints:       chromiumHints("Google Chrome"),
+		HighEntropyHints:  chromiumHighEntropy("Google Chrome"),
+		PseudoHeaderOrder: chromiumPseudoOrder,
+		TLS:               chromiumTLS,
+		HTTP2:             chromiumHTTP2,
+		RequestTemplates:  chromiumRequests(),
+		Since:             map[string]int{"Priority": 124},
+	})
+	mustRegister(&Profile{
+		Name: "firefox",
+		Template: []HeaderField{
+			{"User-Agent", ""},
+			{"Accept", acceptHTML},
+			{"Accept-Language", "en-US,en;q=0.5"},
+			{"Accept-Encoding", acceptEncoding},
+			{"Origin", ""},
+			{"DNT", "1"},
+			{"Connection", "keep-alive"},
+			{"Referer", ""},
+			{"Upgrade-Insecure-Requests", "1"},
+			{"Sec-Fetch-Dest", "document"},
+			{"Sec-Fetch-Mode", "navigate"},
+			{"Sec-Fetch-Site", "none"},
+			{"Sec-Fetch-User", "?1"},
+			{"Sec-GPC", "1"},
+		},
+		UserAgents:        firefoxUserAgents,
+		PseudoHeaderOrder: firefoxPseudoOrder,
+		TLS:               firefoxTLS,
+		HTTP2:             firefoxHTTP2,
+		RequestTemplates:  firefoxRequests(),
+		AcceptLanguage:    firefoxLanguages,
+		Since:             map[string]int{"Priority": 128},
+	})
+	mustRegister(&Profile{
+		Name:              "edge",
+		Template:          chromiumTemplate(),
+		UserAgents:        edgeUserAgents,
+		ClientHints:       chromiumHints("Microsoft Edge"),
+		HighEntropyHints:  chromiumHighEntropy("Microsoft Edge"),
+		PseudoHeaderOrder: chromiumPseudoOrder,
+		TLS:               chromiumTLS,
+		HTTP2:             chromiumHTTP2,
+		RequestTemplates:  chromiumRequests(),
+		Since:             map[string]int{"Priority": 124},
+	})
+	mustRegister(&Profile{
+		Name:              "opera",
+		Template:          chromiumTemplate(),
+		UserAgents:        operaUserAgents,
+		ClientHints:       chromiumHints("Opera"),
+		HighEntropyHints:  chromiumHighEntropy("Opera"),
+		PseudoHeaderOrder: chromiumPseudoOrder,
+		TLS:               chromiumTLS,
+		HTTP2:             chromiumHTTP2,
+		RequestTemplates:  chromiumRequests(),
+		Since:             map[string]int{"Priority": 124},
+	})
+	mustRegister(&Profile{
+		Name:              "brave",
+		Template:          chromiumTemplate(HeaderField{"Sec-GPC", "1"}),
+		UserAgents:        braveUserAgents,
+		ClientHints:       chromiumHints("Brave"),
+		HighEntropyHints:  chromiumHighEntropy("Brave"),
+		PseudoHeaderOrder: chromiumPseudoOrder,
+		TLS:               chromiumTLS,
+		HTTP2:             chromiumHTTP2,
+		RequestTemplates:  chromiumRequests(HeaderField{"Sec-GPC", "1"}),
+		Since:             map[string]int{"Priority": 124},
+	})
+	mustRegister(&Profile{
+		Name: "safari",
+		Template: []HeaderField{
+			{"Accept", acceptSafariHTML},
+			{"Sec-Fetch-Site", "none"},
+			{"Origin", ""},
+			{"Sec-Fetch-Mode", "navigate"},
+			{"User-Agent", ""},
+			{"Referer", ""},
+			{"Accept-Language", "en-US,en;q=0.9"},
+			{"Sec-Fetch-Dest", "document"},
+			{"Accept-Encoding", acceptEncoding},
+			{"Connection", "keep-alive"},
+		},
+		UserAgents:        safariUserAgents,
+		PseudoHeaderOrder: safariPseudoOrder,
+		HTTP2:             safariHTTP2,
+		RequestTemplates:  safariRequests(),
+	})
+}
+
+// chromiumTemplate is the navigation template shared by the chromium
+// based browsers other than chrome. extra is inserted after Accept,
+// which is where brave puts Sec-GPC.
+func chromiumTemplate(extra ...HeaderField) []HeaderField {
+	tmpl := []HeaderField{
+		{"Connection", "keep-alive"},
+		{"sec-ch-ua", ""},
+		{"sec-ch-ua-arch", ""},
+		{"sec-ch-ua-bitness", ""},
+		{"sec-ch-ua-full-version", ""},
+		{"sec-ch-ua-full-version-list", ""},
+		{"sec-ch-ua-mobile", "?0"},
+		{"sec-ch-ua-model", ""},
+		{"sec-ch-ua-platform", ""},
+		{"sec-ch-ua-platform-version", ""},
+		{"sec-ch-ua-wow64", ""},
+		{"Upgrade-Insecure-Requests", "1"},
+		{"Origin", ""},
+		{"User-Agent", ""},
+		{"Accept", acceptChromiumHTML},
+	}
+	tmpl = append(tmpl, extra...)
+	return append(tmpl,
+		HeaderField{"Sec-Fetch-Site", "none"},
+		HeaderField{"Sec-Fetch-Mode", "navigate"},
+		HeaderField{"Sec-Fetch-User", "?1"},
+		HeaderField{"Sec-Fetch-Dest", "document"},
+		HeaderField{"Referer", ""},
+		HeaderField{"Accept-Encoding", acceptEncoding},
+		HeaderField{"Accept-Language", "en-US,en;q=0.9"},
+	)
+}
+
+func platformHint(osys string) string {
+	switch osys {
+	case "m":
+		return `"macOS"`
+	case "l":
+		return `"Linux"`
+	case "a":
+		return `"Android"`
+	case "i":
+		return `"iOS"`
+	default:
+		return `"Windows"`
+	}
+}
+
+var androidDevice = regexp.MustCompile(`Android [\d.]+; ([^;)]+)\)`)
+
+// androidModel returns the device model from an android ua, e.g.
+// "Pixel 7" from "(Linux; Android 13; Pixel 7)".
+func androidModel(ua string) string {
+	m := androidDevice.FindStringSubmatch(ua)
+	if m == nil {
+		return ""
+	}
+	return m[1]
+}
+
+// chromium builds its GREASE brand from these, seeded by the major
+// version, so the brand, its version and the brand order all rotate
+// together from one release to the next.
+var (
+	greaseChars    = []string{" ", "(", ":", "-", ".", "/", ")", ";", "=", "?", "_"}
+	greaseVersions = []string{"8", "99", "24"}
+	greaseOrders   = [][3]int{{0, 1, 2}, {0, 2, 1}, {1, 0, 2}, {1, 2, 0}, {2, 0, 1}, {2, 1, 0}}
+)
+
+// brandList builds a sec-ch-ua brand list for a browser called brand
+// running on chromium. With full set it gives full versions, as sent in
+// sec-ch-ua-full-version-list, rather than majors.
+func brandList(brand, version, chromium string, full bool) string {
+	seed := major(chromium)
+	grease := "Not" + greaseChars[seed%len(greaseChars)] + "A" + greaseChars[(seed+1)%len(greaseChars)] + "Brand"
+	greaseVersion := greaseVersions[seed%len(greaseVersions)]
+	order := greaseOrders[seed%len(greaseOrders)]
+	// the rotation only came in with 105; the majors before it sent
+	// fixed lists.
+	switch {
+	case seed < 103:
+		grease, greaseVersion, order = " Not A;Brand", "99", [3]int{0, 1, 2}
+	case seed == 103:
+		grease, greaseVersion, order = ".Not/A)Brand", "99", [3]int{0, 2, 1}
+	case seed == 104:
+		grease, greaseVersion, order = " Not A;Brand", "99", [3]int{1, 0, 2}
+	}
+	if full {
+		greaseVersion += ".0.0.0"
+	} else {
+		version = strconv.Itoa(major(version))
+		chromium = strconv.Itoa(seed)
+	}
+	var brands [3]string
+	brands[order[0]] = fmt.Sprintf("%q;v=%q", grease, greaseVersion)
+	brands[order[1]] = fmt.Sprintf("%q;v=%q", "Chromium", chromium)
+	brands[order[2]] = fmt.Sprintf("%q;v=%q", brand, version)
+	return strings.Join(brands[:], ", ")
+}
+
+// major returns the major part of a dotted version, or 0.
+func major(version string) int {
+	n, _ := strconv.Atoi(strings.SplitN(version, ".", 2)[0])
+	return n
+}
+
+// chromiumHints returns the client hints a chromium based browser that
+// calls itself brand in sec-ch-ua sends by default. Everything is
+// derived from the chosen UserAgent so the hints always agree with the
+// ua.
+func chromiumHints(brand string) func(ua UserAgent) map[string]string {
+	return func(ua UserAgent) map[string]string {
+		chromium := ua.Chromium
+		if chromium == "" {
+			chromium = ua.Version
+		}
+		hints := map[string]string{
+			"sec-ch-ua":          brandList(brand, ua.Version, chromium, false),
+			"sec-ch-ua-mobile":   "?0",
+			"sec-ch-ua-platform": platformHint(ua.OS),
+		}
+		if ua.OS == "a" {
+			hints["sec-ch-ua-mobile"] = "?1"
+			hints["sec-ch-ua-model"] = strconv.Quote(androidModel(ua.Value))
+		}
+		return hints
+	}
+}
+
+// chromiumHighEntropy returns the hints a chromium based browser only
+// sends once a site asks for them with Accept-CH.
+func chromiumHighEntropy(brand string) func(ua UserAgent) map[string]string {
+	return func(ua UserAgent) map[string]string {
+		chromium := ua.Chromium
+		if chromium == "" {
+			chromium = ua.Version
+		}
+		hints := map[string]string{
+			"sec-ch-ua-arch":              `"x86"`,
+			"sec-ch-ua-bitness":           `"64"`,
+			"sec-ch-ua-full-version":      strconv.Quote(ua.Version),
+			"sec-ch-ua-full-version-list": brandList(brand, ua.Version, chromium, true),
+			"sec-ch-ua-model":             `""`,
+			"sec-ch-ua-platform-version":  strconv.Quote(platformVersion(ua)),
+			"sec-ch-ua-wow64":             "?0",
+		}
+		if ua.OS == "a" {
+			hints["sec-ch-ua-arch"] = `""`
+			hints["sec-ch-ua-bitness"] = `""`
+			hints["sec-ch-ua-model"] = strconv.Quote(androidModel(ua.Value))
+		}
+		if strings.Contains(ua.Value, "WOW64") {
+			// a 32 bit build on 64 bit windows.
+			hints["sec-ch-ua-bitness"] = `"32"`
+			hints["sec-ch-ua-wow64"] = "?1"
+		}
+		return hints
+	}
+}
+
+var (
+	macVersion     = regexp.MustCompile(`Mac OS X (\d+)[._](\d+)(?:[._](\d+))?`)
+	androidVersion = regexp.MustCompile(`Android (\d+)(?:\.(\d+))?(?:\.(\d+))?`)
+	windowsVersion = regexp.MustCompile(`Windows NT (\d+\.\d+)`)
+)
+
+// platformVersion returns sec-ch-ua-platform-version for ua, as far as
+// the ua gives it away. windows reports its api contract version rather
+// than the NT one, and linux reports nothing we could know.
+func platformVersion(ua UserAgent) string {
+	version := func(m []string) string {
+		parts := []string{"0", "0", "0"}
+		for i, p := range m[1:] {
+			if p != "" {
+				parts[i] = p
+			}
+		}
+		return strings.Join(parts, ".")
+	}
+	switch ua.OS {
+	case "m":
+		if m := macVersion.FindStringSubmatch(ua.Value); m != nil {
+			return version(m)
+		}
+	case "a":
+		if m := androidVersion.FindStringSubmatch(ua.Value); m != nil {
+			return version(m)
+		}
+	case "w":
+		m := windowsVersion.FindStringSubmatch(ua.Value)
+		if m == nil {
+			break
+		}
+		switch m[1] {
+		case "6.1":
+			return "0.1.0"
+		case "6.2":
+			return "0.2.0"
+		case "6.3":
+			return "0.3.0"
+		}
+		return "10.0.0"
+	}
+	return ""
+}
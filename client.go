@@ -3,16 +3,30 @@ package fuzzyHelpers
 import (
 	"crypto/tls"
 	"net/http"
-	"net/url"
+	"strings"
 	"time"
 )
 
 type clientOptions struct {
-	allowRedirects bool
-	connections    int
-	noSkip         bool
-	proxy          string
-	timeout        int
+	acceptCH           *ClientHintStore
+	allowRedirects     bool
+	connections        int
+	fingerprint        string
+	handshaker         TLSHandshaker
+	h2Fingerprint      string
+	identity           *Identity
+	noSkip             bool
+	ordered            bool
+	proxies            []string
+	proxyChain         []string
+	proxyConnectHeader http.Header
+	proxyCooldown      time.Duration
+	proxyFailures      int
+	proxyStrategy      ProxyStrategy
+	rateLimit          *RateLimit
+	retry              *RetryPolicy
+	throttle           *Throttle
+	timeout            int
 }
 
 type optionClient func(*clientOptions)
@@ -44,19 +58,40 @@ func NewClient(opts ...optionClient) *http.Client {
 	if c.noSkip {
 		tr.TLSClientConfig.InsecureSkipVerify = false
 	}
-	if c.proxy != "" {
-		parsed, err := url.Parse(c.proxy)
-		// if error, won't set a proxy
-		if err == nil {
-			tr.Proxy = http.ProxyURL(parsed)
-		}
-	}
 	if c.allowRedirects {
 		client.CheckRedirect = nil
 	}
 	if c.timeout > 0 {
 		client.Timeout = time.Duration(c.timeout) * time.Millisecond
 	}
+	if c.identity != nil {
+		client.Jar = c.identity
+	}
+	base := func(tr *http.Transport) http.RoundTripper {
+		if c.ordered || c.fingerprint != "" || c.handshaker != nil || c.h2Fingerprint != "" {
+			return &decodeTransport{next: newOrderedTransport(tr, c)}
+		}
+		return &decodeTransport{next: tr}
+	}
+	if len(c.proxies) > 0 || len(c.proxyChain) > 0 {
+		// each proxy gets its own transport, so connections made through
+		// one aren't reused for requests meant for another.
+		client.Transport = &proxyTransport{pool: newProxyPool(c, tr, base)}
+	} else {
+		client.Transport = base(tr)
+	}
+	if c.throttle != nil {
+		client.Transport = &throttleTransport{next: client.Transport, throttle: c.throttle}
+	}
+	if c.rateLimit != nil {
+		client.Transport = newRateLimitTransport(client.Transport, *c.rateLimit)
+	}
+	if c.acceptCH != nil {
+		client.Transport = &acceptCHTransport{next: client.Transport, store: c.acceptCH}
+	}
+	if c.retry != nil {
+		client.Transport = &retryTransport{next: client.Transport, policy: *c.retry}
+	}
 	return client
 }
 
@@ -75,12 +110,15 @@ func WithNoSkip(v bool) optionClient {
 	}
 }
 
+// WithProxy sends requests through the proxy p, or with WithProxies,
+// adds it to the pool. If p doesn't parse (see ParseProxy), requests
+// fail with the parse error rather than going out without a proxy.
 func WithProxy(p string) optionClient {
 	return func(c *clientOptions) {
 		if p == "" {
 			return
 		}
-		c.proxy = p
+		c.proxies = append(c.proxies, p)
 	}
 }
 
@@ -90,6 +128,53 @@ func WithAllowRedirects(r bool) optionClient {
 	}
 }
 
+// WithOrderedHeaders installs a transport that sends headers in the
+// order, and with the spelling, of the browser profile that generated
+// them, for both HTTP/1.1 and HTTP/2.
+func WithOrderedHeaders(b bool) optionClient {
+	return func(c *clientOptions) {
+		c.ordered = b
+	}
+}
+
+// WithTLSFingerprint makes the client send a browser's ClientHello,
+// written with utls so its JA3 is the browser's. Pass a profile name
+// (e.g. "chrome") to always use that profile's ClientHello, or "auto"
+// to use the one belonging to the profile that generated each
+// request's headers. Like WithOrderedHeaders it installs the client's
+// own transport.
+func WithTLSFingerprint(profile string) optionClient {
+	return func(c *clientOptions) {
+		c.fingerprint = strings.ToLower(profile)
+	}
+}
+
+// WithHTTP2Fingerprint makes the client open http2 connections the way
+// a browser does: its SETTINGS, connection window, PRIORITY frames and
+// stream priorities. It takes a profile name or "auto", like
+// WithTLSFingerprint, and also installs the client's own transport.
+func WithHTTP2Fingerprint(profile string) optionClient {
+	return func(c *clientOptions) {
+		c.h2Fingerprint = strings.ToLower(profile)
+	}
+}
+
+// WithIdentity stores the client's cookies in id, so they're dropped
+// when the identity rotates.
+func WithIdentity(id *Identity) optionClient {
+	return func(c *clientOptions) {
+		c.identity = id
+	}
+}
+
+// WithTLSHandshaker replaces the tls handshake used by the client's
+// own transport, e.g. to send a ClientHello of your own.
+func WithTLSHandshaker(h TLSHandshaker) optionClient {
+	return func(c *clientOptions) {
+		c.handshaker = h
+	}
+}
+
 func WithTimeout(t int) optionClient {
 	return func(c *clientOptions) {
 		if t <= 0 {
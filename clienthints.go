@@ -0,0 +1,185 @@
+package fuzzyHelpers
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// ClientHintStore remembers which client hints each origin has asked
+// for with Accept-CH, the way a browser does, so later requests to the
+// origin can include them. It's safe for concurrent use.
+type ClientHintStore struct {
+	mu      sync.RWMutex
+	origins map[string][]string
+}
+
+func NewClientHintStore() *ClientHintStore {
+	return &ClientHintStore{origins: map[string][]string{}}
+}
+
+// Remember records the Accept-CH header of a response from u. Each
+// Accept-CH replaces what the origin asked for before. Like browsers,
+// it's only honored from secure origins.
+func (s *ClientHintStore) Remember(u *url.URL, h http.Header) {
+	vs, ok := h["Accept-Ch"]
+	if !ok || !secureOrigin(u) {
+		return
+	}
+	hints := parseHintList(strings.Join(vs, ","))
+	s.mu.Lock()
+	s.origins[origin(u)] = hints
+	s.mu.Unlock()
+}
+
+// Hints returns the client hints u's origin has asked for, lowercased.
+func (s *ClientHintStore) Hints(u *url.URL) []string {
+	if s == nil || u == nil {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]string(nil), s.origins[origin(u)]...)
+}
+
+// parseHintList splits an Accept-CH or Critical-CH value.
+func parseHintList(v string) []string {
+	var hints []string
+	for _, hint := range strings.Split(v, ",") {
+		hint = strings.ToLower(strings.TrimSpace(hint))
+		if hint != "" {
+			hints = append(hints, hint)
+		}
+	}
+	return hints
+}
+
+// secureOrigin reports whether browsers treat u as a secure context.
+func secureOrigin(u *url.URL) bool {
+	if u.Scheme == "https" {
+		return true
+	}
+	host := u.Hostname()
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// highEntropy returns the values of the named hints p would send with
+// ua, leaving out any it doesn't know.
+func highEntropy(p *Profile, ua UserAgent, names []string) map[string]string {
+	if p.HighEntropyHints == nil || len(names) == 0 {
+		return nil
+	}
+	all := p.HighEntropyHints(ua)
+	hints := map[string]string{}
+	for _, name := range names {
+		if v, ok := all[name]; ok {
+			hints[name] = v
+		}
+	}
+	return hints
+}
+
+// WithClientHints adds the high entropy hints that the WithURL url's
+// origin asked for in s to generated headers.
+func WithClientHints(s *ClientHintStore) optionHeaders {
+	return func(h *headers) {
+		h.hintStore = s
+	}
+}
+
+// WithAcceptCH makes the client remember Accept-CH responses in s and
+// add the hints they ask for to later requests to the same origin,
+// derived from each request's User-Agent. A Critical-CH response that
+// asks for hints the request lacked is retried with them, once, as
+// chromium does.
+func WithAcceptCH(s *ClientHintStore) optionClient {
+	return func(c *clientOptions) {
+		c.acceptCH = s
+	}
+}
+
+// acceptCHTransport implements WithAcceptCH.
+type acceptCHTransport struct {
+	next  http.RoundTripper
+	store *ClientHintStore
+}
+
+func (t *acceptCHTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	sent := t.addHints(req, t.store.Hints(req.URL))
+	resp, err := t.next.RoundTrip(sent)
+	if err != nil {
+		return resp, err
+	}
+	t.store.Remember(req.URL, resp.Header)
+	critical := resp.Header.Get("Critical-Ch")
+	if critical == "" || !replayable(req) {
+		return resp, nil
+	}
+	retry := t.addHints(req, t.store.Hints(req.URL))
+	missing := false
+	for _, hint := range parseHintList(critical) {
+		if headerValue(sent.Header, hint) == "" && headerValue(retry.Header, hint) != "" {
+			missing = true
+			break
+		}
+	}
+	if !missing {
+		return resp, nil
+	}
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return resp, nil
+		}
+		retry.Body = body
+	}
+	io.CopyN(io.Discard, resp.Body, 4<<10)
+	resp.Body.Close()
+	return t.next.RoundTrip(retry)
+}
+
+// addHints returns req with the named hints added, if its User-Agent
+// belongs to a profile that sends them. Hints already set are kept.
+func (t *acceptCHTransport) addHints(req *http.Request, names []string) *http.Request {
+	if len(names) == 0 {
+		return req
+	}
+	p := matchProfile(req.Header)
+	if p == nil {
+		return req
+	}
+	uaValue := headerValue(req.Header, "User-Agent")
+	var hints map[string]string
+	for _, ua := range p.UserAgents {
+		if ua.Value == uaValue {
+			hints = highEntropy(p, ua, names)
+			break
+		}
+	}
+	// a ua loaded with WithUserAgents isn't in any profile, so go by
+	// what it says about itself.
+	if hints == nil {
+		if browser, ua, ok := parseUserAgent(uaValue); ok {
+			if p, ok := LookupProfile(browser); ok {
+				hints = highEntropy(p, ua, names)
+			}
+		}
+	}
+	if len(hints) == 0 {
+		return req
+	}
+	r2 := req.Clone(req.Context())
+	for name, v := range hints {
+		if headerValue(r2.Header, name) == "" {
+			r2.Header[name] = []string{v}
+		}
+	}
+	return r2
+}
@@ -0,0 +1,207 @@
+package fuzzyHelpers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestHighEntropyHints(t *testing.T) {
+	t.Parallel()
+	p, _ := LookupProfile("chrome")
+	tests := []struct {
+		ua                                      UserAgent
+		arch, platform, version, bitness, wow64 string
+	}{
+		{
+			UserAgent{OS: "w", Version: "110.0.5481.177", Value: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/110.0.0.0 Safari/537.36"},
+			`"x86"`, `"10.0.0"`, `"110.0.5481.177"`, `"64"`, "?0",
+		},
+		{
+			UserAgent{OS: "w", Version: "110.0.5481.177", Value: "Mozilla/5.0 (Windows NT 10.0; WOW64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/110.0.0.0 Safari/537.36"},
+			`"x86"`, `"10.0.0"`, `"110.0.5481.177"`, `"32"`, "?1",
+		},
+		{
+			UserAgent{OS: "m", Version: "108.0.5359.124", Value: "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/108.0.0.0 Safari/537.36"},
+			`"x86"`, `"10.15.7"`, `"108.0.5359.124"`, `"64"`, "?0",
+		},
+		{
+			UserAgent{OS: "a", Version: "112.0.5615.137", Value: "Mozilla/5.0 (Linux; Android 13; Pixel 7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/112.0.0.0 Mobile Safari/537.36"},
+			`""`, `"13.0.0"`, `"112.0.5615.137"`, `""`, "?0",
+		},
+	}
+	for _, tt := range tests {
+		hints := p.HighEntropyHints(tt.ua)
+		if got := hints["sec-ch-ua-arch"]; got != tt.arch {
+			t.Errorf("%s: got arch %s want %s", tt.ua.OS, got, tt.arch)
+		}
+		if got := hints["sec-ch-ua-platform-version"]; got != tt.platform {
+			t.Errorf("%s: got platform version %s want %s", tt.ua.OS, got, tt.platform)
+		}
+		if got := hints["sec-ch-ua-bitness"]; got != tt.bitness {
+			t.Errorf("%s: got bitness %s want %s", tt.ua.Value, got, tt.bitness)
+		}
+		if got := hints["sec-ch-ua-wow64"]; got != tt.wow64 {
+			t.Errorf("%s: got wow64 %s want %s", tt.ua.Value, got, tt.wow64)
+		}
+		if got := hints["sec-ch-ua-full-version"]; got != tt.version {
+			t.Errorf("%s: got full version %s want %s", tt.ua.OS, got, tt.version)
+		}
+		want := `"Google Chrome";v=` + tt.version
+		if got := hints["sec-ch-ua-full-version-list"]; !strings.Contains(got, want) {
+			t.Errorf("%s: full version list %s doesn't contain %s", tt.ua.OS, got, want)
+		}
+	}
+}
+
+func TestClientHintStore(t *testing.T) {
+	t.Parallel()
+	s := NewClientHintStore()
+	site := mustURL(t, "https://example.com/a")
+	s.Remember(site, http.Header{"Accept-Ch": {"Sec-CH-UA-Arch, Sec-CH-UA-Bitness"}})
+	if got := strings.Join(s.Hints(mustURL(t, "https://example.com/b")), ","); got != "sec-ch-ua-arch,sec-ch-ua-bitness" {
+		t.Errorf("got %s", got)
+	}
+	if got := s.Hints(mustURL(t, "https://sub.example.com/")); len(got) != 0 {
+		t.Errorf("hints leaked to another origin: %v", got)
+	}
+	s.Remember(site, http.Header{"Accept-Ch": {"Sec-CH-UA-Model"}})
+	if got := strings.Join(s.Hints(site), ","); got != "sec-ch-ua-model" {
+		t.Errorf("Accept-CH didn't replace the earlier one, got %s", got)
+	}
+	s.Remember(site, http.Header{"Content-Type": {"text/html"}})
+	if got := strings.Join(s.Hints(site), ","); got != "sec-ch-ua-model" {
+		t.Errorf("a response without Accept-CH changed the hints, got %s", got)
+	}
+	plain := mustURL(t, "http://example.com/")
+	s.Remember(plain, http.Header{"Accept-Ch": {"Sec-CH-UA-Arch"}})
+	if got := s.Hints(plain); len(got) != 0 {
+		t.Errorf("Accept-CH over plain http was honored: %v", got)
+	}
+}
+
+func TestWithClientHints(t *testing.T) {
+	t.Parallel()
+	s := NewClientHintStore()
+	s.Remember(mustURL(t, "https://example.com/"), http.Header{"Accept-Ch": {"Sec-CH-UA-Platform-Version, Sec-CH-UA-Full-Version-List"}})
+
+	headers := NewHeaders(WithProfile("chrome"), WithURL("https://example.com/x"), WithClientHints(s)).Headers()
+	for _, name := range []string{"sec-ch-ua-platform-version", "sec-ch-ua-full-version-list"} {
+		if _, ok := headers[name]; !ok {
+			t.Errorf("wanted %s", name)
+		}
+	}
+	if v, ok := headers["sec-ch-ua-arch"]; ok {
+		t.Errorf("got sec-ch-ua-arch %v, which wasn't asked for", v)
+	}
+
+	other := NewHeaders(WithProfile("chrome"), WithURL("https://other.test/"), WithClientHints(s)).Headers()
+	if v, ok := other["sec-ch-ua-platform-version"]; ok {
+		t.Errorf("got %v for an origin that didn't ask", v)
+	}
+	ff := NewHeaders(WithProfile("firefox"), WithURL("https://example.com/x"), WithClientHints(s)).Headers()
+	for k := range ff {
+		if strings.HasPrefix(strings.ToLower(k), "sec-ch-") {
+			t.Errorf("firefox sent %s", k)
+		}
+	}
+}
+
+// hintServer asks for client hints and records the requests it gets.
+type hintServer struct {
+	*httptest.Server
+	mu   sync.Mutex
+	reqs []http.Header
+}
+
+func newHintServer(t *testing.T, critical bool) *hintServer {
+	t.Helper()
+	s := &hintServer{}
+	s.Server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.reqs = append(s.reqs, r.Header.Clone())
+		s.mu.Unlock()
+		w.Header().Set("Accept-CH", "Sec-CH-UA-Arch, Sec-CH-UA-Platform-Version")
+		if critical {
+			w.Header().Set("Critical-CH", "Sec-CH-UA-Platform-Version")
+		}
+		w.Write([]byte("ok"))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestAcceptCH(t *testing.T) {
+	t.Parallel()
+	s := newHintServer(t, false)
+	c := NewClient(WithAcceptCH(NewClientHintStore()))
+	h := NewHeaders(WithProfile("chrome"), WithOS("w"))
+	for i := 0; i < 2; i++ {
+		req, _ := http.NewRequest("GET", s.URL, nil)
+		req.Header = h.Headers()
+		resp, err := c.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if v := s.reqs[0].Get("Sec-CH-UA-Arch"); v != "" {
+		t.Errorf("first request already had sec-ch-ua-arch %s", v)
+	}
+	if v := s.reqs[1].Get("Sec-CH-UA-Arch"); v != `"x86"` {
+		t.Errorf("got sec-ch-ua-arch %q on the second request", v)
+	}
+	if v := s.reqs[1].Get("Sec-CH-UA-Platform-Version"); v != `"10.0.0"` {
+		t.Errorf("got sec-ch-ua-platform-version %q on the second request", v)
+	}
+}
+
+func TestCriticalCH(t *testing.T) {
+	t.Parallel()
+	s := newHintServer(t, true)
+	c := NewClient(WithAcceptCH(NewClientHintStore()))
+	req, _ := http.NewRequest("GET", s.URL, nil)
+	req.Header = NewHeaders(WithProfile("edge"), WithOS("m")).Headers()
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.reqs) != 2 {
+		t.Fatalf("got %d requests want the original and a retry", len(s.reqs))
+	}
+	if v := s.reqs[1].Get("Sec-CH-UA-Platform-Version"); v == "" {
+		t.Error("retry didn't include the critical hint")
+	}
+}
+
+func TestAcceptCHIgnoresUnknownAgents(t *testing.T) {
+	t.Parallel()
+	s := newHintServer(t, true)
+	c := NewClient(WithAcceptCH(NewClientHintStore()))
+	for i := 0; i < 2; i++ {
+		req, _ := http.NewRequest("GET", s.URL, nil)
+		req.Header.Set("User-Agent", "curl/8.0")
+		resp, err := c.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.reqs) != 2 {
+		t.Errorf("got %d requests, wanted no retries", len(s.reqs))
+	}
+	for _, h := range s.reqs {
+		if v := h.Get("Sec-CH-UA-Arch"); v != "" {
+			t.Errorf("sent sec-ch-ua-arch %s for an unknown ua", v)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package fuzzyHelpers
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// acceptEncoding is the Accept-Encoding the browser profiles send.
+const acceptEncoding = "gzip, deflate, br"
+
+// decodeTransport decodes gzip, deflate and br responses to requests
+// whose Accept-Encoding came from the profile that generated their
+// headers, the way net/http does for the gzip it asks for itself. A
+// request with an Accept-Encoding of the caller's own gets the body as
+// sent.
+type decodeTransport struct {
+	next http.RoundTripper
+}
+
+func (t *decodeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil || req.Method == http.MethodHead || !profileEncoding(req.Header) {
+		return resp, err
+	}
+	decodeBody(resp)
+	return resp, nil
+}
+
+func (t *decodeTransport) CloseIdleConnections() {
+	if c, ok := t.next.(interface{ CloseIdleConnections() }); ok {
+		c.CloseIdleConnections()
+	}
+}
+
+// profileEncoding reports whether h's Accept-Encoding is the one the
+// profile that generated h sends.
+func profileEncoding(h http.Header) bool {
+	ae := headerValue(h, "Accept-Encoding")
+	if ae == "" {
+		return false
+	}
+	p := matchProfile(h)
+	return p != nil && fieldValue(p.requestTemplate(h), "Accept-Encoding") == ae
+}
+
+// decodeBody swaps resp's body for a decoded one if it's gzip, deflate
+// or br, dropping Content-Encoding and Content-Length as they no longer
+// apply.
+func decodeBody(resp *http.Response) {
+	encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding")))
+	switch encoding {
+	case "gzip", "x-gzip", "deflate", "br":
+	default:
+		return
+	}
+	resp.Body = &decodedBody{body: resp.Body, encoding: encoding}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+}
+
+// decodedBody decodes body on the first Read, so an empty body or one
+// that's never read doesn't block or fail the round trip.
+type decodedBody struct {
+	body     io.ReadCloser
+	encoding string
+	r        io.Reader
+	err      error
+}
+
+func (b *decodedBody) Read(p []byte) (int, error) {
+	if b.r == nil && b.err == nil {
+		b.r, b.err = newDecoder(b.body, b.encoding)
+	}
+	if b.err != nil {
+		return 0, b.err
+	}
+	return b.r.Read(p)
+}
+
+func (b *decodedBody) Close() error {
+	if c, ok := b.r.(io.Closer); ok {
+		c.Close()
+	}
+	return b.body.Close()
+}
+
+// newDecoder returns a reader decoding r. deflate is meant to be
+// zlib-wrapped, but some servers send it raw, so the header decides.
+func newDecoder(r io.Reader, encoding string) (io.Reader, error) {
+	switch encoding {
+	case "br":
+		return brotli.NewReader(r), nil
+	case "deflate":
+		br := bufio.NewReader(r)
+		if h, err := br.Peek(2); err == nil && h[0]&0x0f == 8 && (uint16(h[0])<<8|uint16(h[1]))%31 == 0 {
+			return zlib.NewReader(br)
+		}
+		return flate.NewReader(br), nil
+	}
+	return gzip.NewReader(r)
+}
@@ -0,0 +1,98 @@
+package fuzzyHelpers
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+func TestDecodeResponses(t *testing.T) {
+	t.Parallel()
+	const body = "hello, fuzzy world"
+	encoders := map[string]func(io.Writer) io.WriteCloser{
+		"gzip": func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) },
+		"br":   func(w io.Writer) io.WriteCloser { return brotli.NewWriter(w) },
+		"zlib": func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) },
+		"raw deflate": func(w io.Writer) io.WriteCloser {
+			fw, _ := flate.NewWriter(w, flate.DefaultCompression)
+			return fw
+		},
+	}
+	encodings := map[string]string{"gzip": "gzip", "br": "br", "zlib": "deflate", "raw deflate": "deflate"}
+	for name, enc := range encoders {
+		var buf bytes.Buffer
+		w := enc(&buf)
+		io.WriteString(w, body)
+		w.Close()
+		encoded := buf.Bytes()
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Encoding", encodings[name])
+			w.Write(encoded)
+		}))
+		defer ts.Close()
+
+		for variant, c := range clientVariants() {
+			for _, profile := range []string{"chrome", "firefox", "safari"} {
+				req, _ := http.NewRequest("GET", ts.URL, nil)
+				req.Header = NewHeaders(WithProfile(profile), WithOS("w")).Headers()
+				resp, err := c.Do(req)
+				if err != nil {
+					t.Fatalf("%s, %s: %v", name, variant, err)
+				}
+				got, err := io.ReadAll(resp.Body)
+				resp.Body.Close()
+				if err != nil || string(got) != body {
+					t.Errorf("%s, %s, %s: got %q, %v want %q", name, variant, profile, got, err, body)
+				}
+				if resp.Header.Get("Content-Encoding") != "" || !resp.Uncompressed {
+					t.Errorf("%s, %s, %s: Content-Encoding %q left on a decoded response", name, variant, profile, resp.Header.Get("Content-Encoding"))
+				}
+			}
+
+			// an Accept-Encoding of the caller's own gets the body as sent.
+			req, _ := http.NewRequest("GET", ts.URL, nil)
+			req.Header = NewHeaders(ChromeOnly(true)).Headers()
+			req.Header.Set("Accept-Encoding", encodings[name])
+			resp, err := c.Do(req)
+			if err != nil {
+				t.Fatalf("%s, %s: %v", name, variant, err)
+			}
+			got, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if !bytes.Equal(got, encoded) {
+				t.Errorf("%s, %s: decoded a response to the caller's own Accept-Encoding", name, variant)
+			}
+		}
+	}
+}
+
+func TestDecodeEmptyBody(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "br")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+	for variant, c := range clientVariants() {
+		for _, method := range []string{"GET", "HEAD"} {
+			req, _ := http.NewRequest(method, ts.URL, nil)
+			req.Header = NewHeaders(ChromeOnly(true)).Headers()
+			resp, err := c.Do(req)
+			if err != nil {
+				t.Fatalf("%s %s: %v", variant, method, err)
+			}
+			io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusNoContent {
+				t.Errorf("%s %s: got %s", variant, method, resp.Status)
+			}
+		}
+	}
+}
@@ -1,3 +1,16 @@
 module github.com/davemolk/fuzzyHelpers
 
-go 1.19
+go 1.24
+
+require (
+	github.com/andybalholm/brotli v1.1.0
+	github.com/refraction-networking/utls v1.8.2
+	golang.org/x/net v0.38.0
+	golang.org/x/text v0.23.0
+)
+
+require (
+	github.com/klauspost/compress v1.18.0 // indirect
+	golang.org/x/crypto v0.36.0 // indirect
+	golang.org/x/sys v0.31.0 // indirect
+)
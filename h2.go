@@ -0,0 +1,914 @@
+package fuzzyHelpers
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+)
+
+// errH2Unusable means the connection can't take new streams and the
+// request should go out on a new one. Nothing was sent when it's returned.
+var errH2Unusable = errors.New("http2: connection unusable")
+
+const (
+	h2DefaultWindow = 65535
+	h2DefaultFrame  = 16384
+	h2DefaultTable  = 4096
+)
+
+// HTTP2Setting is a single SETTINGS parameter, e.g. {1, 65536} for
+// SETTINGS_HEADER_TABLE_SIZE.
+type HTTP2Setting struct {
+	ID  uint16
+	Val uint32
+}
+
+// HTTP2Priority is the priority of a stream, as sent in PRIORITY frames
+// and on HEADERS. Weight is the wire value, one less than the weight.
+type HTTP2Priority struct {
+	StreamID  uint32
+	StreamDep uint32
+	Weight    uint8
+	Exclusive bool
+}
+
+// HTTP2Fingerprint describes how a browser opens an http2 connection:
+// the SETTINGS it sends (in order), the connection WINDOW_UPDATE that
+// follows, any PRIORITY frames after that, and the priority it puts on
+// each request's HEADERS. Requests start on the first odd stream after
+// the ones used by Priorities, as they do in firefox.
+type HTTP2Fingerprint struct {
+	Settings       []HTTP2Setting
+	WindowUpdate   uint32
+	Priorities     []HTTP2Priority
+	HeaderPriority *HTTP2Priority
+}
+
+// defaultHTTP2 is used when no profile fingerprint applies. the window
+// is handed back as response bodies are read, so it's also how much of
+// a response can pile up unread.
+var defaultHTTP2 = &HTTP2Fingerprint{
+	Settings: []HTTP2Setting{
+		{uint16(http2.SettingEnablePush), 0},
+		{uint16(http2.SettingInitialWindowSize), 4 << 20},
+	},
+	WindowUpdate: 4 << 20,
+}
+
+var (
+	chromiumHTTP2 = &HTTP2Fingerprint{
+		Settings: []HTTP2Setting{
+			{uint16(http2.SettingHeaderTableSize), 65536},
+			{uint16(http2.SettingEnablePush), 0},
+			{uint16(http2.SettingInitialWindowSize), 6291456},
+			{uint16(http2.SettingMaxHeaderListSize), 262144},
+		},
+		WindowUpdate:   15663105,
+		HeaderPriority: &HTTP2Priority{Weight: 255, Exclusive: true},
+	}
+	firefoxHTTP2 = &HTTP2Fingerprint{
+		Settings: []HTTP2Setting{
+			{uint16(http2.SettingHeaderTableSize), 65536},
+			{uint16(http2.SettingInitialWindowSize), 131072},
+			{uint16(http2.SettingMaxFrameSize), 16384},
+		},
+		WindowUpdate: 12517377,
+		// firefox's dependency tree of idle placeholder streams.
+		Priorities: []HTTP2Priority{
+			{StreamID: 3, Weight: 200},
+			{StreamID: 5, Weight: 100},
+			{StreamID: 7, Weight: 0},
+			{StreamID: 9, StreamDep: 7, Weight: 0},
+			{StreamID: 11, StreamDep: 3, Weight: 0},
+			{StreamID: 13, Weight: 240},
+		},
+		HeaderPriority: &HTTP2Priority{StreamDep: 13, Weight: 41},
+	}
+	safariHTTP2 = &HTTP2Fingerprint{
+		Settings: []HTTP2Setting{
+			{uint16(http2.SettingEnablePush), 0},
+			{uint16(http2.SettingInitialWindowSize), 4194304},
+			{uint16(http2.SettingMaxConcurrentStreams), 100},
+		},
+		WindowUpdate:   10485760,
+		HeaderPriority: &HTTP2Priority{Weight: 254},
+	}
+)
+
+// Akamai returns the fingerprint in the format popularized by Akamai's
+// http2 fingerprinting paper, given the pseudo-header order that goes
+// with it: SETTINGS|WINDOW_UPDATE|PRIORITY|PSEUDO_HEADER_ORDER.
+func (fp *HTTP2Fingerprint) Akamai(pseudoOrder []string) string {
+	var settings, prios, pseudo []string
+	for _, s := range fp.Settings {
+		settings = append(settings, fmt.Sprintf("%d:%d", s.ID, s.Val))
+	}
+	for _, p := range fp.Priorities {
+		excl := 0
+		if p.Exclusive {
+			excl = 1
+		}
+		prios = append(prios, fmt.Sprintf("%d:%d:%d:%d", p.StreamID, excl, p.StreamDep, int(p.Weight)+1))
+	}
+	if len(prios) == 0 {
+		prios = []string{"0"}
+	}
+	for _, name := range pseudoOrder {
+		pseudo = append(pseudo, name[1:2])
+	}
+	return fmt.Sprintf("%s|%d|%s|%s", strings.Join(settings, ";"), fp.WindowUpdate, strings.Join(prios, ","), strings.Join(pseudo, ","))
+}
+
+// connection-specific headers aren't allowed in http2.
+var h2Forbidden = map[string]bool{
+	"connection":        true,
+	"host":              true,
+	"keep-alive":        true,
+	"proxy-connection":  true,
+	"transfer-encoding": true,
+	"upgrade":           true,
+}
+
+// h2Conn is a minimal http2 client connection. It exists because
+// golang.org/x/net/http2 doesn't let callers choose the order of the
+// header block.
+type h2Conn struct {
+	conn net.Conn
+	fr   *http2.Framer
+	bw   *bufio.Writer
+
+	// wmu serializes frame writes and hpack state.
+	wmu  sync.Mutex
+	hbuf bytes.Buffer
+	henc *hpack.Encoder
+
+	mu           sync.Mutex
+	cond         *sync.Cond
+	streams      map[uint32]*h2Stream
+	nextID       uint32
+	maxStreams   uint32
+	maxFrameSize uint32
+	peerWindow   int32 // initial send window for new streams
+	connWindow   int32 // send window for the connection
+	goAway       bool
+	err          error
+
+	// recvWindow is the window each stream starts with on our side and
+	// connRecv the connection's, as sent in the preface. connUnacked is
+	// what's been read from bodies but not handed back to the server
+	// yet.
+	recvWindow  int32
+	connRecv    int32
+	connUnacked int32
+
+	// idleTimer closes the connection once it's had no streams for
+	// idleTimeout.
+	idleTimeout   time.Duration
+	idleTimer     *time.Timer
+	headerTimeout time.Duration
+	onClose       func()
+
+	// priority is put on every HEADERS frame, if set.
+	priority *HTTP2Priority
+}
+
+// h2Options are the settings an h2Conn takes from the transport.
+type h2Options struct {
+	// idleTimeout closes the connection once it's been without streams
+	// that long, and headerTimeout fails requests whose response headers
+	// take longer than that to arrive. 0 means no limit.
+	idleTimeout   time.Duration
+	headerTimeout time.Duration
+	// onClose is called once the connection is closed.
+	onClose func()
+}
+
+type h2Stream struct {
+	id     uint32
+	req    *http.Request
+	resp   *http.Response
+	gotHdr chan struct{}
+	window int32 // send window
+
+	// guarded by h2Conn.mu. unacked is what's been read from buf but
+	// not handed back to the stream's window yet.
+	buf     bytes.Buffer
+	unacked int32
+	done    bool
+	err     error
+}
+
+// newH2Conn starts an http2 connection over c, opening it the way fp
+// says (defaultHTTP2 if nil).
+func newH2Conn(c net.Conn, fp *HTTP2Fingerprint, opts h2Options) (*h2Conn, error) {
+	if fp == nil {
+		fp = defaultHTTP2
+	}
+	cc := &h2Conn{
+		conn:          c,
+		bw:            bufio.NewWriter(c),
+		streams:       map[uint32]*h2Stream{},
+		nextID:        1,
+		maxStreams:    100,
+		maxFrameSize:  h2DefaultFrame,
+		peerWindow:    h2DefaultWindow,
+		connWindow:    h2DefaultWindow,
+		recvWindow:    h2DefaultWindow,
+		connRecv:      h2DefaultWindow + int32(fp.WindowUpdate),
+		idleTimeout:   opts.idleTimeout,
+		headerTimeout: opts.headerTimeout,
+		onClose:       opts.onClose,
+		priority:      fp.HeaderPriority,
+	}
+	cc.cond = sync.NewCond(&cc.mu)
+	cc.henc = hpack.NewEncoder(&cc.hbuf)
+	cc.fr = http2.NewFramer(cc.bw, bufio.NewReader(c))
+	table := uint32(h2DefaultTable)
+	cc.fr.MaxHeaderListSize = 1 << 20
+	settings := make([]http2.Setting, len(fp.Settings))
+	for i, st := range fp.Settings {
+		settings[i] = http2.Setting{ID: http2.SettingID(st.ID), Val: st.Val}
+		switch settings[i].ID {
+		case http2.SettingHeaderTableSize:
+			table = st.Val
+		case http2.SettingMaxHeaderListSize:
+			cc.fr.MaxHeaderListSize = st.Val
+		case http2.SettingInitialWindowSize:
+			cc.recvWindow = int32(st.Val)
+		}
+	}
+	cc.fr.ReadMetaHeaders = hpack.NewDecoder(table, nil)
+	for _, p := range fp.Priorities {
+		if p.StreamID >= cc.nextID {
+			cc.nextID = p.StreamID + 2
+		}
+	}
+
+	if _, err := io.WriteString(cc.bw, http2.ClientPreface); err != nil {
+		c.Close()
+		return nil, err
+	}
+	err := cc.fr.WriteSettings(settings...)
+	if err == nil && fp.WindowUpdate > 0 {
+		err = cc.fr.WriteWindowUpdate(0, fp.WindowUpdate)
+	}
+	for _, p := range fp.Priorities {
+		if err != nil {
+			break
+		}
+		err = cc.fr.WritePriority(p.StreamID, http2.PriorityParam{
+			StreamDep: p.StreamDep,
+			Exclusive: p.Exclusive,
+			Weight:    p.Weight,
+		})
+	}
+	if err == nil {
+		err = cc.bw.Flush()
+	}
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+	if cc.idleTimeout > 0 {
+		cc.idleTimer = time.AfterFunc(cc.idleTimeout, cc.closeIdle)
+	}
+	go cc.readLoop()
+	return cc, nil
+}
+
+func (cc *h2Conn) usable() bool {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	return cc.err == nil && !cc.goAway && cc.nextID < 1<<31-1
+}
+
+func (cc *h2Conn) close() {
+	cc.conn.Close()
+}
+
+// closeIdle closes the connection if it still has no streams.
+func (cc *h2Conn) closeIdle() {
+	cc.mu.Lock()
+	idle := len(cc.streams) == 0 && cc.err == nil
+	if idle {
+		cc.err = errors.New("http2: idle connection timed out")
+	}
+	cc.mu.Unlock()
+	if idle {
+		cc.conn.Close()
+	}
+}
+
+// full reports whether the server's stream limit keeps a request
+// waiting; cc.mu must be held.
+func (cc *h2Conn) full() bool {
+	return cc.err == nil && !cc.goAway && uint32(len(cc.streams)) >= cc.maxStreams
+}
+
+// forget drops stream id, starting the idle timer if it was the last
+// one; cc.mu must be held.
+func (cc *h2Conn) forget(id uint32) {
+	delete(cc.streams, id)
+	if len(cc.streams) == 0 && cc.idleTimer != nil {
+		cc.idleTimer.Reset(cc.idleTimeout)
+	}
+}
+
+func (cc *h2Conn) roundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	cc.mu.Lock()
+	if cc.full() {
+		// cond.Wait can't watch ctx, so wake it up when ctx is done.
+		waited := make(chan struct{})
+		go func() {
+			select {
+			case <-ctx.Done():
+				cc.mu.Lock()
+				cc.cond.Broadcast()
+				cc.mu.Unlock()
+			case <-waited:
+			}
+		}()
+		for cc.full() && ctx.Err() == nil {
+			cc.cond.Wait()
+		}
+		close(waited)
+	}
+	if err := ctx.Err(); err != nil {
+		cc.mu.Unlock()
+		closeBody(req)
+		return nil, err
+	}
+	if cc.err != nil || cc.goAway || cc.nextID >= 1<<31-1 {
+		cc.mu.Unlock()
+		return nil, errH2Unusable
+	}
+	cs := &h2Stream{
+		id:     cc.nextID,
+		req:    req,
+		gotHdr: make(chan struct{}),
+		window: cc.peerWindow,
+	}
+	cc.nextID += 2
+	cc.streams[cs.id] = cs
+	if cc.idleTimer != nil {
+		cc.idleTimer.Stop()
+	}
+	cc.mu.Unlock()
+
+	stop := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			cc.cancel(cs, ctx.Err())
+		case <-stop:
+		}
+	}()
+	hasBody := req.Body != nil && req.Body != http.NoBody
+	err := cc.writeHeaders(cs, req, !hasBody)
+	if err == nil && hasBody {
+		err = cc.writeBody(cs, req.Body)
+	}
+	if hasBody {
+		req.Body.Close()
+	}
+	if err != nil {
+		close(stop)
+		cc.cancel(cs, err)
+		return nil, err
+	}
+	var timeout <-chan time.Time
+	if cc.headerTimeout > 0 {
+		timer := time.NewTimer(cc.headerTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case <-cs.gotHdr:
+	case <-ctx.Done():
+	case <-timeout:
+		close(stop)
+		cc.cancel(cs, errResponseHeaderTimeout)
+		return nil, errResponseHeaderTimeout
+	}
+	cc.mu.Lock()
+	resp, serr := cs.resp, cs.err
+	cc.mu.Unlock()
+	if resp == nil {
+		close(stop)
+		if serr == nil {
+			serr = ctx.Err()
+		}
+		// the watcher may not have got to ctx before stop.
+		cc.cancel(cs, serr)
+		return nil, serr
+	}
+	resp.Body = &h2Body{cc: cc, cs: cs, stop: stop}
+	return resp, nil
+}
+
+// writeHeaders encodes the request's header block in the profile's
+// order: pseudo-headers first, then the regular headers.
+func (cc *h2Conn) writeHeaders(cs *h2Stream, req *http.Request, endStream bool) error {
+	p := matchProfile(req.Header)
+	method := req.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	pseudo := map[string]string{
+		":method":    method,
+		":authority": requestHost(req),
+		":scheme":    req.URL.Scheme,
+		":path":      req.URL.RequestURI(),
+	}
+	cc.wmu.Lock()
+	defer cc.wmu.Unlock()
+	cc.hbuf.Reset()
+	for _, name := range pseudoOrder(p) {
+		cc.henc.WriteField(hpack.HeaderField{Name: name, Value: pseudo[name]})
+	}
+	sentLength := false
+	for _, f := range orderedFields(p, req.Header, "") {
+		name := strings.ToLower(f.Name)
+		if h2Forbidden[name] {
+			continue
+		}
+		if name == "te" && f.Value != "trailers" {
+			continue
+		}
+		sentLength = sentLength || name == "content-length"
+		cc.henc.WriteField(hpack.HeaderField{Name: name, Value: f.Value})
+	}
+	switch {
+	case sentLength:
+	case req.ContentLength > 0:
+		cc.henc.WriteField(hpack.HeaderField{Name: "content-length", Value: strconv.FormatInt(req.ContentLength, 10)})
+	case endStream && methodSendsLength(method):
+		cc.henc.WriteField(hpack.HeaderField{Name: "content-length", Value: "0"})
+	}
+
+	block := cc.hbuf.Bytes()
+	max := int(cc.frameSize())
+	first := true
+	for len(block) > 0 || first {
+		chunk := block
+		if len(chunk) > max {
+			chunk = chunk[:max]
+		}
+		block = block[len(chunk):]
+		var err error
+		if first {
+			param := http2.HeadersFrameParam{
+				StreamID:      cs.id,
+				BlockFragment: chunk,
+				EndStream:     endStream,
+				EndHeaders:    len(block) == 0,
+			}
+			if cc.priority != nil {
+				param.Priority = http2.PriorityParam{
+					StreamDep: cc.priority.StreamDep,
+					Exclusive: cc.priority.Exclusive,
+					Weight:    cc.priority.Weight,
+				}
+			}
+			err = cc.fr.WriteHeaders(param)
+			first = false
+		} else {
+			err = cc.fr.WriteContinuation(cs.id, len(block) == 0, chunk)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return cc.bw.Flush()
+}
+
+func (cc *h2Conn) frameSize() uint32 {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	return cc.maxFrameSize
+}
+
+// writeBody sends body as DATA frames, waiting on flow control.
+func (cc *h2Conn) writeBody(cs *h2Stream, body io.Reader) error {
+	buf := make([]byte, h2DefaultFrame)
+	for {
+		n, rerr := io.ReadFull(body, buf)
+		if rerr == io.ErrUnexpectedEOF {
+			rerr = io.EOF
+		}
+		if rerr != nil && rerr != io.EOF {
+			return rerr
+		}
+		data := buf[:n]
+		for len(data) > 0 {
+			allowed, err := cc.awaitWindow(cs, int32(len(data)))
+			if err != nil {
+				return err
+			}
+			cc.wmu.Lock()
+			last := rerr == io.EOF && int(allowed) == len(data)
+			err = cc.fr.WriteData(cs.id, last, data[:allowed])
+			if err == nil {
+				err = cc.bw.Flush()
+			}
+			cc.wmu.Unlock()
+			if err != nil {
+				return err
+			}
+			data = data[allowed:]
+		}
+		if rerr == io.EOF {
+			if n == 0 {
+				cc.wmu.Lock()
+				err := cc.fr.WriteData(cs.id, true, nil)
+				if err == nil {
+					err = cc.bw.Flush()
+				}
+				cc.wmu.Unlock()
+				return err
+			}
+			return nil
+		}
+	}
+}
+
+// awaitWindow blocks until some of want bytes may be sent on cs and
+// takes them out of the windows.
+func (cc *h2Conn) awaitWindow(cs *h2Stream, want int32) (int32, error) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	for {
+		if cs.err != nil {
+			return 0, cs.err
+		}
+		if cc.err != nil {
+			return 0, cc.err
+		}
+		allowed := want
+		if cs.window < allowed {
+			allowed = cs.window
+		}
+		if cc.connWindow < allowed {
+			allowed = cc.connWindow
+		}
+		if allowed > 0 {
+			cs.window -= allowed
+			cc.connWindow -= allowed
+			return allowed, nil
+		}
+		cc.cond.Wait()
+	}
+}
+
+// cancel aborts cs with err and resets it on the wire if it's still open.
+func (cc *h2Conn) cancel(cs *h2Stream, err error) {
+	cc.mu.Lock()
+	_, open := cc.streams[cs.id]
+	if cs.err == nil && !cs.done {
+		cs.err = err
+	}
+	cc.forget(cs.id)
+	cc.signalHeaders(cs)
+	cc.cond.Broadcast()
+	cc.mu.Unlock()
+	if open {
+		cc.write(func() error { return cc.fr.WriteRSTStream(cs.id, http2.ErrCodeCancel) })
+	}
+}
+
+func (cc *h2Conn) readLoop() {
+	var err error
+	for {
+		var f http2.Frame
+		f, err = cc.fr.ReadFrame()
+		if err != nil {
+			break
+		}
+		if err = cc.handle(f); err != nil {
+			break
+		}
+	}
+	cc.mu.Lock()
+	cc.err = fmt.Errorf("http2: connection closed: %w", err)
+	for id, cs := range cc.streams {
+		if !cs.done && cs.err == nil {
+			cs.err = cc.err
+		}
+		cc.signalHeaders(cs)
+		delete(cc.streams, id)
+	}
+	if cc.idleTimer != nil {
+		cc.idleTimer.Stop()
+	}
+	cc.cond.Broadcast()
+	cc.mu.Unlock()
+	cc.conn.Close()
+	if cc.onClose != nil {
+		cc.onClose()
+	}
+}
+
+// signalHeaders wakes roundTrip once cs has a response or an error.
+// Callers hold cc.mu.
+func (cc *h2Conn) signalHeaders(cs *h2Stream) {
+	select {
+	case <-cs.gotHdr:
+	default:
+		close(cs.gotHdr)
+	}
+}
+
+func (cc *h2Conn) handle(f http2.Frame) error {
+	switch f := f.(type) {
+	case *http2.MetaHeadersFrame:
+		return cc.handleHeaders(f)
+	case *http2.DataFrame:
+		return cc.handleData(f)
+	case *http2.SettingsFrame:
+		if f.IsAck() {
+			return nil
+		}
+		tableSize := uint32(0)
+		cc.mu.Lock()
+		f.ForeachSetting(func(s http2.Setting) error {
+			switch s.ID {
+			case http2.SettingHeaderTableSize:
+				tableSize = s.Val
+			case http2.SettingMaxFrameSize:
+				cc.maxFrameSize = s.Val
+			case http2.SettingMaxConcurrentStreams:
+				cc.maxStreams = s.Val
+			case http2.SettingInitialWindowSize:
+				delta := int32(s.Val) - cc.peerWindow
+				for _, cs := range cc.streams {
+					cs.window += delta
+				}
+				cc.peerWindow = int32(s.Val)
+			}
+			return nil
+		})
+		cc.cond.Broadcast()
+		cc.mu.Unlock()
+		return cc.write(func() error {
+			if tableSize > 0 {
+				cc.henc.SetMaxDynamicTableSizeLimit(tableSize)
+			}
+			return cc.fr.WriteSettingsAck()
+		})
+	case *http2.PingFrame:
+		if f.IsAck() {
+			return nil
+		}
+		return cc.write(func() error { return cc.fr.WritePing(true, f.Data) })
+	case *http2.WindowUpdateFrame:
+		cc.mu.Lock()
+		if f.StreamID == 0 {
+			cc.connWindow += int32(f.Increment)
+		} else if cs := cc.streams[f.StreamID]; cs != nil {
+			cs.window += int32(f.Increment)
+		}
+		cc.cond.Broadcast()
+		cc.mu.Unlock()
+	case *http2.RSTStreamFrame:
+		cc.mu.Lock()
+		if cs := cc.streams[f.StreamID]; cs != nil {
+			if !cs.done {
+				cs.err = http2.StreamError{StreamID: f.StreamID, Code: f.ErrCode}
+			}
+			cc.forget(f.StreamID)
+			cc.signalHeaders(cs)
+		}
+		cc.cond.Broadcast()
+		cc.mu.Unlock()
+	case *http2.GoAwayFrame:
+		cc.mu.Lock()
+		cc.goAway = true
+		for id, cs := range cc.streams {
+			if id > f.LastStreamID {
+				cs.err = fmt.Errorf("http2: server sent GOAWAY (%v)", f.ErrCode)
+				cc.forget(id)
+				cc.signalHeaders(cs)
+			}
+		}
+		cc.cond.Broadcast()
+		cc.mu.Unlock()
+	}
+	return nil
+}
+
+func (cc *h2Conn) handleHeaders(f *http2.MetaHeadersFrame) error {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	cs := cc.streams[f.StreamID]
+	if cs == nil {
+		return nil
+	}
+	if cs.resp != nil {
+		// trailers
+		for _, hf := range f.RegularFields() {
+			if cs.resp.Trailer == nil {
+				cs.resp.Trailer = http.Header{}
+			}
+			cs.resp.Trailer.Add(http.CanonicalHeaderKey(hf.Name), hf.Value)
+		}
+		if f.StreamEnded() {
+			cs.done = true
+			cc.forget(cs.id)
+			cc.cond.Broadcast()
+		}
+		return nil
+	}
+	status, err := strconv.Atoi(f.PseudoValue("status"))
+	if err != nil {
+		cs.err = fmt.Errorf("http2: bad :status %q", f.PseudoValue("status"))
+		cc.forget(cs.id)
+		cc.signalHeaders(cs)
+		return nil
+	}
+	if status >= 100 && status < 200 {
+		// informational, wait for the real response
+		return nil
+	}
+	header := http.Header{}
+	for _, hf := range f.RegularFields() {
+		header.Add(http.CanonicalHeaderKey(hf.Name), hf.Value)
+	}
+	length := int64(-1)
+	if v := header.Get("Content-Length"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			length = n
+		}
+	}
+	if f.StreamEnded() {
+		length = 0
+		cs.done = true
+		cc.forget(cs.id)
+		cc.cond.Broadcast()
+	}
+	cs.resp = &http.Response{
+		Status:        strconv.Itoa(status) + " " + http.StatusText(status),
+		StatusCode:    status,
+		Proto:         "HTTP/2.0",
+		ProtoMajor:    2,
+		Header:        header,
+		ContentLength: length,
+		Request:       cs.req,
+	}
+	cc.signalHeaders(cs)
+	return nil
+}
+
+// handleData buffers a DATA frame for the stream's body. The window it
+// takes up is handed back as the body is read; only padding, and frames
+// for streams that are gone, are handed straight back.
+func (cc *h2Conn) handleData(f *http2.DataFrame) error {
+	data := f.Data()
+	padding := f.Length - uint32(len(data))
+	connInc, streamInc := padding, uint32(0)
+	reset := false
+	cc.mu.Lock()
+	cs := cc.streams[f.StreamID]
+	switch {
+	case cs == nil:
+		connInc = f.Length
+	case int64(cs.buf.Len())+int64(cs.unacked)+int64(len(data)) > int64(cc.recvWindow):
+		// the server ignored our window.
+		cs.err = http2.StreamError{StreamID: cs.id, Code: http2.ErrCodeFlowControl}
+		cc.forget(cs.id)
+		cc.signalHeaders(cs)
+		connInc, reset = f.Length, true
+	default:
+		cs.buf.Write(data)
+		if f.StreamEnded() {
+			cs.done = true
+			cc.forget(cs.id)
+		} else {
+			streamInc = padding
+		}
+	}
+	cc.cond.Broadcast()
+	cc.mu.Unlock()
+	if reset {
+		if err := cc.write(func() error { return cc.fr.WriteRSTStream(f.StreamID, http2.ErrCodeFlowControl) }); err != nil {
+			return err
+		}
+	}
+	return cc.windowUpdate(f.StreamID, connInc, streamInc)
+}
+
+// windowUpdate hands conn bytes back to the connection's window and
+// stream bytes to stream id's.
+func (cc *h2Conn) windowUpdate(id, conn, stream uint32) error {
+	if conn == 0 && stream == 0 {
+		return nil
+	}
+	return cc.write(func() error {
+		if conn > 0 {
+			if err := cc.fr.WriteWindowUpdate(0, conn); err != nil {
+				return err
+			}
+		}
+		if stream > 0 {
+			return cc.fr.WriteWindowUpdate(id, stream)
+		}
+		return nil
+	})
+}
+
+// consumed records n bytes of cs's body as read and returns how much
+// of the connection and stream windows to hand back. Like net/http it
+// waits until half a window is owed, rather than updating on every
+// read. cc.mu must be held.
+func (cc *h2Conn) consumed(cs *h2Stream, n int) (conn, stream uint32) {
+	cc.connUnacked += int32(n)
+	if cc.connUnacked >= cc.connRecv/2 {
+		conn, cc.connUnacked = uint32(cc.connUnacked), 0
+	}
+	if cs.done || cs.err != nil {
+		// the stream's closed, so its window doesn't matter any more.
+		return conn, 0
+	}
+	cs.unacked += int32(n)
+	if cs.unacked >= cc.recvWindow/2 {
+		stream, cs.unacked = uint32(cs.unacked), 0
+	}
+	return conn, stream
+}
+
+func (cc *h2Conn) write(fn func() error) error {
+	cc.wmu.Lock()
+	defer cc.wmu.Unlock()
+	if err := fn(); err != nil {
+		return err
+	}
+	return cc.bw.Flush()
+}
+
+// h2Body reads a stream's DATA as it arrives.
+type h2Body struct {
+	cc   *h2Conn
+	cs   *h2Stream
+	stop chan struct{}
+	once sync.Once
+}
+
+func (b *h2Body) Read(p []byte) (int, error) {
+	cc, cs := b.cc, b.cs
+	cc.mu.Lock()
+	for cs.buf.Len() == 0 && !cs.done && cs.err == nil {
+		cc.cond.Wait()
+	}
+	if cs.buf.Len() > 0 {
+		n, _ := cs.buf.Read(p)
+		conn, stream := cc.consumed(cs, n)
+		cc.mu.Unlock()
+		// a failed write shows up as a read error soon enough.
+		cc.windowUpdate(cs.id, conn, stream)
+		return n, nil
+	}
+	err := cs.err
+	cc.mu.Unlock()
+	if err == nil {
+		err = io.EOF
+	}
+	b.finish()
+	return 0, err
+}
+
+func (b *h2Body) Close() error {
+	b.finish()
+	cc, cs := b.cc, b.cs
+	cc.mu.Lock()
+	done := cs.done
+	// what's left unread is dropped, which frees its share of the
+	// connection window as reading it would have.
+	cc.connUnacked += int32(cs.buf.Len())
+	cs.buf.Reset()
+	var conn uint32
+	if cc.connUnacked >= cc.connRecv/2 {
+		conn, cc.connUnacked = uint32(cc.connUnacked), 0
+	}
+	cc.mu.Unlock()
+	if !done {
+		cc.cancel(cs, errors.New("http2: response body closed"))
+	}
+	cc.windowUpdate(0, conn, 0)
+	return nil
+}
+
+// finish stops watching the request context.
+func (b *h2Body) finish() {
+	b.once.Do(func() { close(b.stop) })
+}
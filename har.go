@@ -0,0 +1,266 @@
+package fuzzyHelpers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strings"
+)
+
+// the parts of a HAR file ProfileFromHAR reads.
+type harFile struct {
+	Log struct {
+		Entries []struct {
+			Request harRequest `json:"request"`
+		} `json:"entries"`
+	} `json:"log"`
+}
+
+type harRequest struct {
+	Method  string `json:"method"`
+	URL     string `json:"url"`
+	Headers []struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	} `json:"headers"`
+}
+
+// harVolatile are headers whose value belongs to one request rather
+// than to the browser. Profiles keep their position but not their
+// value.
+var harVolatile = map[string]bool{
+	"authorization":       true,
+	"content-length":      true,
+	"content-type":        true,
+	"cookie":              true,
+	"host":                true,
+	"if-match":            true,
+	"if-modified-since":   true,
+	"if-none-match":       true,
+	"if-range":            true,
+	"if-unmodified-since": true,
+	"origin":              true,
+	"range":               true,
+	"referer":             true,
+}
+
+// ProfileFromHAR derives a profile called name from a HAR file recorded
+// in a browser's dev tools, for use with RegisterProfile. The first
+// page load in the recording becomes the template, and the first
+// request of each other type (see WithRequestType) that type's
+// template, with headers in the order and with the values the browser
+// sent them. The ua and client hints are the ones recorded, high
+// entropy hints included if a site asked for them, and the http2
+// pseudo-header order is taken from the recording when it has one.
+// The tls and http2 fingerprints, which a HAR doesn't record, come from
+// the built in profile of the same browser.
+//
+// Values that belong to a single request (Cookie, Referer, Origin,
+// Authorization, conditional and body headers) are left out, though
+// their position is kept for when they're generated or set.
+func ProfileFromHAR(r io.Reader, name string) (*Profile, error) {
+	var har harFile
+	if err := json.NewDecoder(r).Decode(&har); err != nil {
+		return nil, fmt.Errorf("parsing har: %w", err)
+	}
+	p := &Profile{Name: name, RequestTemplates: map[string][]HeaderField{}}
+	hints := map[string]string{}
+	high := map[string]string{}
+	uaValue := ""
+	for _, entry := range har.Log.Entries {
+		req := entry.Request
+		if uaValue == "" {
+			uaValue = harHeader(req, "user-agent")
+		}
+		if v := harHeader(req, "user-agent"); v == "" || v != uaValue {
+			continue
+		}
+		for _, hd := range req.Headers {
+			lk := strings.ToLower(hd.Name)
+			switch {
+			case !strings.HasPrefix(lk, "sec-ch-"):
+			case lowEntropyHints[lk]:
+				if _, ok := hints[lk]; !ok {
+					hints[lk] = hd.Value
+				}
+			default:
+				if _, ok := high[lk]; !ok {
+					high[lk] = hd.Value
+				}
+			}
+		}
+		kind := harKind(req)
+		if kind == "" {
+			continue
+		}
+		if len(p.PseudoHeaderOrder) == 0 {
+			for _, hd := range req.Headers {
+				if strings.HasPrefix(hd.Name, ":") {
+					p.PseudoHeaderOrder = append(p.PseudoHeaderOrder, hd.Name)
+				}
+			}
+		}
+		if kind == RequestNavigate {
+			if p.Template == nil {
+				p.Template = harTemplate(req, true)
+			}
+			continue
+		}
+		if _, ok := p.RequestTemplates[kind]; !ok {
+			p.RequestTemplates[kind] = harTemplate(req, false)
+		}
+	}
+	if uaValue == "" {
+		return nil, errors.New("har has no requests with a User-Agent")
+	}
+	if p.Template == nil {
+		return nil, errors.New("har has no page load to take the headers from")
+	}
+
+	browser, ua, _ := parseUserAgent(uaValue)
+	if ua.OS == "" {
+		ua.OS = platformOS(hints["sec-ch-ua-platform"])
+	}
+	// the reduced ua only has the major version, the hints the full one.
+	for brand, v := range parseBrands(high["sec-ch-ua-full-version-list"]) {
+		if brand == "Chromium" {
+			ua.Chromium = v
+		} else if !strings.Contains(brand, "Brand") {
+			ua.Version = v
+		}
+	}
+	if v := strings.Trim(high["sec-ch-ua-full-version"], `"`); v != "" {
+		ua.Version = v
+	}
+	if ua.Chromium == ua.Version {
+		ua.Chromium = ""
+	}
+	p.UserAgents = []UserAgent{ua}
+	if len(hints) > 0 {
+		p.ClientHints = func(UserAgent) map[string]string { return hints }
+	}
+	if len(high) > 0 {
+		p.HighEntropyHints = func(UserAgent) map[string]string { return high }
+	}
+	if base, ok := LookupProfile(browser); ok {
+		p.TLS, p.HTTP2, p.AcceptLanguage = base.TLS, base.HTTP2, base.AcceptLanguage
+		if len(p.PseudoHeaderOrder) == 0 {
+			p.PseudoHeaderOrder = base.PseudoHeaderOrder
+		}
+	}
+	return p, nil
+}
+
+// lowEntropyHints are the client hints chromium sends without being
+// asked.
+var lowEntropyHints = map[string]bool{
+	"sec-ch-ua":          true,
+	"sec-ch-ua-mobile":   true,
+	"sec-ch-ua-platform": true,
+}
+
+// platformOS is the WithOS value for a sec-ch-ua-platform value.
+func platformOS(platform string) string {
+	for _, osys := range []string{"l", "m", "w", "a", "i"} {
+		if platformHint(osys) == platform {
+			return osys
+		}
+	}
+	return ""
+}
+
+func harHeader(req harRequest, name string) string {
+	for _, hd := range req.Headers {
+		if strings.EqualFold(hd.Name, name) {
+			return hd.Value
+		}
+	}
+	return ""
+}
+
+// harKind works out the request type of a recorded request, or ""
+// for ones that aren't any of them.
+func harKind(req harRequest) string {
+	if harHeader(req, "x-requested-with") != "" {
+		return RequestXHR
+	}
+	dest, mode := harHeader(req, "sec-fetch-dest"), harHeader(req, "sec-fetch-mode")
+	switch dest {
+	case "document":
+		return RequestNavigate
+	case "iframe":
+		return RequestIframe
+	case "script":
+		return RequestScript
+	case "style":
+		return RequestStyle
+	case "image":
+		return RequestImage
+	case "font":
+		return RequestFont
+	case "empty":
+		if mode == "cors" || mode == "same-origin" {
+			return RequestFetch
+		}
+	case "":
+		// browsers without fetch metadata.
+		if req.Method == "GET" && strings.HasPrefix(harHeader(req, "accept"), "text/html") {
+			return RequestNavigate
+		}
+	}
+	return ""
+}
+
+// harTemplate turns a recorded request into a template. A page load
+// template describes one the user started, so its Sec-Fetch-Site is
+// none whatever the recording says; WithReferrer works out the rest.
+func harTemplate(req harRequest, navigate bool) []HeaderField {
+	var tmpl []HeaderField
+	for _, hd := range req.Headers {
+		if strings.HasPrefix(hd.Name, ":") {
+			continue
+		}
+		name := harName(hd.Name)
+		lk := strings.ToLower(name)
+		v := hd.Value
+		switch {
+		case harVolatile[lk], lk == "user-agent", strings.HasPrefix(lk, "sec-ch-"):
+			v = ""
+		case navigate && lk == "sec-fetch-site":
+			v = "none"
+		}
+		if harHasField(tmpl, name) {
+			continue
+		}
+		tmpl = append(tmpl, HeaderField{name, v})
+	}
+	return tmpl
+}
+
+func harHasField(tmpl []HeaderField, name string) bool {
+	for _, f := range tmpl {
+		if f.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// harName spells a recorded header name the way the built in templates
+// do, which is what the header generation goes by: client hints in
+// lowercase and everything else canonical. http2 recordings are all
+// lowercase, so the spelling they were sent with is lost anyway.
+func harName(name string) string {
+	lk := strings.ToLower(name)
+	switch {
+	case strings.HasPrefix(lk, "sec-ch-"):
+		return lk
+	case lk == "dnt":
+		return "DNT"
+	case lk == "sec-gpc":
+		return "Sec-GPC"
+	}
+	return textproto.CanonicalMIMEHeaderKey(name)
+}
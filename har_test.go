@@ -0,0 +1,162 @@
+package fuzzyHelpers
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const harUA = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/121.0.0.0 Safari/537.36"
+
+// harRecording is a trimmed down chrome 121 recording over http2: a page
+// load, an image and a fetch after the site asked for the full version
+// list.
+const harRecording = `{"log": {"version": "1.2", "entries": [
+{"request": {"method": "GET", "url": "https://example.com/", "httpVersion": "http/2.0", "headers": [
+	{"name": ":method", "value": "GET"},
+	{"name": ":authority", "value": "example.com"},
+	{"name": ":scheme", "value": "https"},
+	{"name": ":path", "value": "/"},
+	{"name": "sec-ch-ua", "value": "\"Not A(Brand\";v=\"99\", \"Google Chrome\";v=\"121\", \"Chromium\";v=\"121\""},
+	{"name": "sec-ch-ua-mobile", "value": "?0"},
+	{"name": "sec-ch-ua-platform", "value": "\"Windows\""},
+	{"name": "upgrade-insecure-requests", "value": "1"},
+	{"name": "user-agent", "value": "` + harUA + `"},
+	{"name": "accept", "value": "text/html,*/*;q=0.8"},
+	{"name": "sec-fetch-site", "value": "same-origin"},
+	{"name": "sec-fetch-mode", "value": "navigate"},
+	{"name": "sec-fetch-user", "value": "?1"},
+	{"name": "sec-fetch-dest", "value": "document"},
+	{"name": "referer", "value": "https://example.com/home"},
+	{"name": "accept-encoding", "value": "gzip, deflate, br"},
+	{"name": "accept-language", "value": "de-DE,de;q=0.9"},
+	{"name": "cookie", "value": "session=abc"},
+	{"name": "priority", "value": "u=0, i"}
+]}},
+{"request": {"method": "GET", "url": "https://example.com/logo.png", "httpVersion": "http/2.0", "headers": [
+	{"name": ":method", "value": "GET"},
+	{"name": ":authority", "value": "example.com"},
+	{"name": ":scheme", "value": "https"},
+	{"name": ":path", "value": "/logo.png"},
+	{"name": "sec-ch-ua", "value": "\"Not A(Brand\";v=\"99\", \"Google Chrome\";v=\"121\", \"Chromium\";v=\"121\""},
+	{"name": "sec-ch-ua-mobile", "value": "?0"},
+	{"name": "user-agent", "value": "` + harUA + `"},
+	{"name": "sec-ch-ua-platform", "value": "\"Windows\""},
+	{"name": "accept", "value": "image/avif,image/webp,*/*;q=0.8"},
+	{"name": "sec-fetch-site", "value": "same-origin"},
+	{"name": "sec-fetch-mode", "value": "no-cors"},
+	{"name": "sec-fetch-dest", "value": "image"},
+	{"name": "referer", "value": "https://example.com/"},
+	{"name": "accept-language", "value": "de-DE,de;q=0.9"},
+	{"name": "priority", "value": "i"}
+]}},
+{"request": {"method": "POST", "url": "https://example.com/api", "httpVersion": "http/2.0", "headers": [
+	{"name": ":method", "value": "POST"},
+	{"name": ":authority", "value": "example.com"},
+	{"name": ":scheme", "value": "https"},
+	{"name": ":path", "value": "/api"},
+	{"name": "content-length", "value": "2"},
+	{"name": "sec-ch-ua", "value": "\"Not A(Brand\";v=\"99\", \"Google Chrome\";v=\"121\", \"Chromium\";v=\"121\""},
+	{"name": "sec-ch-ua-full-version-list", "value": "\"Not A(Brand\";v=\"99.0.0.0\", \"Google Chrome\";v=\"121.0.6167.140\", \"Chromium\";v=\"121.0.6167.140\""},
+	{"name": "sec-ch-ua-mobile", "value": "?0"},
+	{"name": "user-agent", "value": "` + harUA + `"},
+	{"name": "sec-ch-ua-platform", "value": "\"Windows\""},
+	{"name": "content-type", "value": "application/json"},
+	{"name": "accept", "value": "*/*"},
+	{"name": "origin", "value": "https://example.com"},
+	{"name": "sec-fetch-site", "value": "same-origin"},
+	{"name": "sec-fetch-mode", "value": "cors"},
+	{"name": "sec-fetch-dest", "value": "empty"},
+	{"name": "referer", "value": "https://example.com/"},
+	{"name": "accept-language", "value": "de-DE,de;q=0.9"},
+	{"name": "priority", "value": "u=1, i"}
+]}}
+]}}`
+
+func TestProfileFromHAR(t *testing.T) {
+	p, err := ProfileFromHAR(strings.NewReader(harRecording), "chrome121")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantTemplate := []HeaderField{
+		{"sec-ch-ua", ""},
+		{"sec-ch-ua-mobile", ""},
+		{"sec-ch-ua-platform", ""},
+		{"Upgrade-Insecure-Requests", "1"},
+		{"User-Agent", ""},
+		{"Accept", "text/html,*/*;q=0.8"},
+		{"Sec-Fetch-Site", "none"},
+		{"Sec-Fetch-Mode", "navigate"},
+		{"Sec-Fetch-User", "?1"},
+		{"Sec-Fetch-Dest", "document"},
+		{"Referer", ""},
+		{"Accept-Encoding", "gzip, deflate, br"},
+		{"Accept-Language", "de-DE,de;q=0.9"},
+		{"Cookie", ""},
+		{"Priority", "u=0, i"},
+	}
+	if !reflect.DeepEqual(p.Template, wantTemplate) {
+		t.Errorf("got template\n%v\nwant\n%v", p.Template, wantTemplate)
+	}
+	if got := strings.Join(p.PseudoHeaderOrder, ","); got != ":method,:authority,:scheme,:path" {
+		t.Errorf("got pseudo-header order %s", got)
+	}
+	wantUA := UserAgent{Value: harUA, OS: "w", Version: "121.0.6167.140"}
+	if len(p.UserAgents) != 1 || p.UserAgents[0] != wantUA {
+		t.Errorf("got uas %+v", p.UserAgents)
+	}
+	if len(p.RequestTemplates) != 2 || p.RequestTemplates[RequestImage] == nil || p.RequestTemplates[RequestFetch] == nil {
+		t.Errorf("got request templates for %v", reflect.ValueOf(p.RequestTemplates).MapKeys())
+	}
+	chrome, _ := LookupProfile("chrome")
+	if p.TLS != chrome.TLS || p.HTTP2 != chrome.HTTP2 {
+		t.Error("fingerprints weren't taken from chrome")
+	}
+
+	registerTestProfile(t, p)
+	s := NewClientHintStore()
+	s.Remember(mustURL(t, "https://example.com/"), http.Header{"Accept-Ch": {"Sec-CH-UA-Full-Version-List"}})
+	headers := NewHeaders(WithProfile("chrome121"), WithURL("https://example.com/"), WithClientHints(s)).Headers()
+	want := map[string]string{
+		"sec-ch-ua":          `"Not A(Brand";v="99", "Google Chrome";v="121", "Chromium";v="121"`,
+		"sec-ch-ua-platform": `"Windows"`,
+		"User-Agent":         harUA,
+		"Accept-Language":    "de-DE,de;q=0.9",
+		"Sec-Fetch-Site":     "none",
+	}
+	for name, v := range want {
+		if got := headerValue(headers, name); got != v {
+			t.Errorf("got %s %q want %q", name, got, v)
+		}
+	}
+	for _, name := range []string{"Cookie", "Referer", "sec-ch-ua-full-version-list"} {
+		if _, ok := headers[name]; ok {
+			t.Errorf("%s was sent", name)
+		}
+	}
+	fetch := NewHeaders(WithProfile("chrome121"), WithRequestType(RequestFetch), WithURL("https://example.com/api"), WithClientHints(s)).Headers()
+	if got := headerValue(fetch, "sec-ch-ua-full-version-list"); !strings.Contains(got, "121.0.6167.140") {
+		t.Errorf("got full version list %q", got)
+	}
+	if _, ok := fetch["Content-Type"]; ok {
+		t.Error("the recorded Content-Type was sent")
+	}
+	if issues := Lint(headers); len(issues) != 0 {
+		t.Errorf("lint: %v", issues)
+	}
+}
+
+func TestProfileFromHARErrors(t *testing.T) {
+	t.Parallel()
+	for _, bad := range []string{
+		"not json",
+		`{"log": {"entries": []}}`,
+		`{"log": {"entries": [{"request": {"method": "GET", "headers": [{"name": "Accept", "value": "*/*"}]}}]}}`,
+		`{"log": {"entries": [{"request": {"method": "GET", "headers": [{"name": "User-Agent", "value": "x"}, {"name": "Sec-Fetch-Dest", "value": "image"}]}}]}}`,
+	} {
+		if _, err := ProfileFromHAR(strings.NewReader(bad), "x"); err == nil {
+			t.Errorf("%s: wanted an error", bad)
+		}
+	}
+}
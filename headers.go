@@ -2,8 +2,10 @@ package fuzzyHelpers
 
 import (
 	"math/rand"
+	"net/http"
 	"net/url"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -12,8 +14,53 @@ type headers struct {
 	customHeaders   bool
 	ffOnly          bool
 	osys            string
+	anyOS           bool
+	profile         string
+	requestType     string
+	nav             navigation
+	hintStore       *ClientHintStore
+	choice          *Choice
 	suppressHeaders []string
-	headerMap       headerMap
+	// headerMap holds the headers set by options (WithCustomHeaders,
+	// WithURL). it's only written while NewHeaders runs and is copied
+	// into each generated set, which keeps Headers safe to call from
+	// multiple goroutines.
+	headerMap headerMap
+
+	// rng is the private source set by WithSeed or WithRand; nil means
+	// the global one. rand.Rand isn't safe for concurrent use, hence
+	// rngMu.
+	rngMu sync.Mutex
+	rng   *rand.Rand
+
+	// locale is set by WithLocale, locales by WithRandomLocale.
+	locale  string
+	locales []weightedLocale
+
+	// userAgents are the user agents read by WithUserAgents, by
+	// profile, and profiles the copies of those profiles using them.
+	userAgents map[string][]UserAgent
+	profiles   map[string]*Profile
+
+	weights Weights
+
+	// raw is the captured request set by WithRawRequest.
+	raw         *RawRequest
+	rawOverride bool
+
+	// err is the first error an option ran into, see Err.
+	err error
+}
+
+// Choice records the random decisions behind a set of headers. Pass it
+// to WithChoice to generate the same set again.
+type Choice struct {
+	Profile   string
+	OS        string
+	UserAgent UserAgent
+	// Locale is the comma separated list of language tags behind
+	// Accept-Language, or empty for the profile's default.
+	Locale string
 }
 
 type optionHeaders func(*headers)
@@ -28,13 +75,32 @@ func (hm headerMap) add(k, v string) {
 	hm[k] = []string{v}
 }
 
-func (h *headers) suppressOrSet(k, v string) {
+func (h *headers) suppressed(k string) bool {
 	for _, v := range h.suppressHeaders {
 		if k == v {
-			return
+			return true
 		}
 	}
-	h.headerMap[k] = []string{v}
+	return false
+}
+
+func (hm headerMap) clone() headerMap {
+	c := make(headerMap, len(hm))
+	for k, v := range hm {
+		c[k] = append([]string(nil), v...)
+	}
+	return c
+}
+
+func (h *headers) set(hm headerMap, k, v string) {
+	switch {
+	case h.suppressed(k):
+		return
+	case h.customHeaders:
+		hm.add(k, v)
+	default:
+		hm[k] = []string{v}
+	}
 }
 
 func init() {
@@ -46,21 +112,88 @@ func NewHeaders(opts ...optionHeaders) *headers {
 	h := &headers{
 		osys:      "w",
 		headerMap: hd,
+		weights:   DefaultWeights(),
 	}
 	for _, opt := range opts {
 		opt(h)
 	}
+	h.loadProfiles()
+	// resolved here rather than in WithOS so a WithSeed that comes
+	// after it still applies.
+	if h.osys == "any" {
+		h.anyOS = true
+		h.osys = h.randOS()
+	}
 	return h
 }
 
+// intn is rand.Intn on the generator's source.
+func (h *headers) intn(n int) int {
+	if h.rng == nil {
+		return rand.Intn(n)
+	}
+	h.rngMu.Lock()
+	defer h.rngMu.Unlock()
+	return h.rng.Intn(n)
+}
+
+// float64 is rand.Float64 on the generator's source.
+func (h *headers) float64() float64 {
+	if h.rng == nil {
+		return rand.Float64()
+	}
+	h.rngMu.Lock()
+	defer h.rngMu.Unlock()
+	return h.rng.Float64()
+}
+
+// WithSeed gives the generator its own random source seeded with seed,
+// so a run makes the same choices every time. Calls from several
+// goroutines still draw in whatever order they happen to run.
+func WithSeed(seed int64) optionHeaders {
+	return func(h *headers) {
+		h.rng = rand.New(rand.NewSource(seed))
+	}
+}
+
+// WithRand makes the generator draw from r instead of the global
+// source. r shouldn't be used elsewhere while the generator is in use.
+func WithRand(r *rand.Rand) optionHeaders {
+	return func(h *headers) {
+		h.rng = r
+	}
+}
+
+// WithRequestType generates headers for a kind of request other than
+// a page load: RequestFetch, RequestXHR, RequestScript, RequestStyle,
+// RequestImage, RequestFont or RequestIframe. Unknown types, and
+// profiles without a template for the type, give navigation headers.
+func WithRequestType(kind string) optionHeaders {
+	return func(h *headers) {
+		h.requestType = strings.ToLower(kind)
+	}
+}
+
+// WithChoice pins the profile, os and user agent to those of an earlier
+// Generate call, making the output reproducible.
+func WithChoice(c Choice) optionHeaders {
+	return func(h *headers) {
+		h.choice = &c
+		h.profile = strings.ToLower(c.Profile)
+		if c.OS != "" {
+			h.osys = c.OS
+		}
+	}
+}
+
 func WithOS(osys string) optionHeaders {
 	return func(h *headers) {
 		osys = strings.ToLower(osys)
 		switch osys {
-		case "l", "m", "w":
+		case "l", "m", "w", "a", "i":
 			h.osys = osys
 		case "any":
-			h.osys = h.randOS()
+			h.osys = "any"
 		default:
 			h.osys = "w"
 		}
@@ -94,11 +227,6 @@ func SuppressHeaders(data string) optionHeaders {
 	}
 }
 
-func (h *headers) randOS() string {
-	options := []string{"l", "m", "w"}
-	return options[rand.Intn(3)]
-}
-
 func WithURL(s string) optionHeaders {
 	return func(h *headers) {
 		u, err := url.ParseRequestURI(s)
@@ -107,6 +235,29 @@ func WithURL(s string) optionHeaders {
 			return
 		}
 		h.headerMap["Host"] = []string{u.Host}
+		h.nav.to = u
+	}
+}
+
+// WithReferrer sets the page the request comes from. Referer, Origin
+// and Sec-Fetch-Site are then derived from it and the WithURL url
+// (assumed to be on the same origin if not given), following the
+// browser's default referrer policy.
+func WithReferrer(s string) optionHeaders {
+	return func(h *headers) {
+		u, err := url.ParseRequestURI(s)
+		if err != nil {
+			return
+		}
+		h.nav.from = u
+	}
+}
+
+// WithMethod sets the request's method. Along with WithReferrer it
+// decides whether Origin is sent.
+func WithMethod(m string) optionHeaders {
+	return func(h *headers) {
+		h.nav.method = strings.ToUpper(m)
 	}
 }
 
@@ -122,259 +273,209 @@ func FirefoxOnly(b bool) optionHeaders {
 	}
 }
 
-func (h *headers) Headers() map[string][]string {
+// WithProfile selects a registered profile by name. Unknown names are
+// ignored and a profile is chosen at random instead.
+func WithProfile(name string) optionHeaders {
+	return func(h *headers) {
+		h.profile = strings.ToLower(name)
+	}
+}
+
+// Headers returns a new set of headers from a freshly chosen profile
+// each time it's called. It's safe for concurrent use, and the result
+// belongs to the caller.
+func (h *headers) Headers() http.Header {
+	hm, _ := h.Generate()
+	return hm
+}
+
+// Generate is Headers, also reporting what was chosen so the same
+// headers can be produced again with WithChoice.
+func (h *headers) Generate() (http.Header, Choice) {
+	return h.generateOn(h.osys)
+}
+
+func (h *headers) generateOn(osys string) (http.Header, Choice) {
+	hm := h.headerMap.clone()
+	c := h.generate(hm, h.pickProfile(osys), osys)
+	return http.Header(hm), c
+}
+
+// pickProfile resolves the profile to use. A captured User-Agent that
+// WithRawRequest lets win decides it, then WithProfile, then
+// ChromeOnly, then FirefoxOnly, otherwise any registered profile
+// available on the chosen os, going by the browser weights.
+func (h *headers) pickProfile(osys string) *Profile {
+	if p, _, ok := h.rawUserAgent(); ok {
+		return p
+	}
+	if p, ok := h.lookup(h.profile); ok {
+		return p
+	}
 	switch {
 	case h.chromeOnly:
-		h.chrome()
+		if p, ok := h.lookup("chrome"); ok {
+			return p
+		}
 	case h.ffOnly:
-		h.firefox()
-	case rand.Intn(2) == 1:
-		h.firefox()
-	default:
-		h.chrome()
+		if p, ok := h.lookup("firefox"); ok {
+			return p
+		}
 	}
-	return h.headerMap
+	// only choose between profiles that exist on the requested os
+	// (e.g. no safari on windows) unless none do.
+	var candidates []*Profile
+	for _, name := range Profiles() {
+		p, _ := h.lookup(name)
+		if p.supports(osys) {
+			candidates = append(candidates, p)
+		}
+	}
+	if len(candidates) == 0 {
+		for _, name := range Profiles() {
+			p, _ := h.lookup(name)
+			candidates = append(candidates, p)
+		}
+	}
+	weights := make([]float64, len(candidates))
+	for i, p := range candidates {
+		weights[i] = h.weights.browser(p.Name)
+	}
+	return candidates[h.pick(weights)]
 }
 
-func Headers() map[string][]string {
-	return NewHeaders().Headers()
+// Apply generates a set of headers for req and sets them on it. The
+// request's url and method stand in for WithURL and WithMethod when
+// those weren't given. Headers req already has are left alone, names
+// keep the spelling the browser uses (e.g. "sec-ch-ua"), which is how
+// net/http writes them over HTTP/1.1, and a Host header goes into
+// req.Host, where net/http looks for it, unless the caller already
+// pointed req.Host elsewhere or set Host in req.Header. Like Generate,
+// it reports what it chose.
+func (h *headers) Apply(req *http.Request) Choice {
+	nav := h.nav
+	if nav.to == nil {
+		nav.to = req.URL
+	}
+	if nav.method == "" {
+		nav.method = strings.ToUpper(req.Method)
+	}
+	hm := h.headerMap.clone()
+	p := h.pickProfile(h.osys)
+	c := h.choose(p, h.osys)
+	h.build(hm, p, c, h.requestType, nav)
+	applyHeaders(req, http.Header(hm))
+	return c
 }
 
-func (h *headers) firefox() {
-	uAgent := h.ffUA()
-	switch {
-	case h.customHeaders && len(h.suppressHeaders) > 0:
-		h.suppressOrSet("User-Agent", uAgent)
-		h.suppressOrSet("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,*/*;q=0.8")
-		h.suppressOrSet("Accept-Language", "en-US,en;q=0.5")
-		h.suppressOrSet("DNT", "1")
-		h.suppressOrSet("Connection", "keep-alive")
-		h.suppressOrSet("Upgrade-Insecure-Requests", "1")
-		h.suppressOrSet("Sec-Fetch-Dest", "document")
-		h.suppressOrSet("Sec-Fetch-Mode", "navigate")
-		h.suppressOrSet("Sec-Fetch-Site", "none")
-		h.suppressOrSet("Sec-Fetch-User", "?1")
-		h.suppressOrSet("Sec-GCP", "1")
-	case h.customHeaders:
-		h.headerMap.add("User-Agent", uAgent)
-		h.headerMap.add("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,*/*;q=0.8")
-		h.headerMap.add("Accept-Language", "en-US,en;q=0.5")
-		h.headerMap.add("DNT", "1")
-		h.headerMap.add("Connection", "keep-alive")
-		h.headerMap.add("Upgrade-Insecure-Requests", "1")
-		h.headerMap.add("Sec-Fetch-Dest", "document")
-		h.headerMap.add("Sec-Fetch-Mode", "navigate")
-		h.headerMap.add("Sec-Fetch-Site", "none")
-		h.headerMap.add("Sec-Fetch-User", "?1")
-		h.headerMap.add("Sec-GCP", "1")
-	case len(h.suppressHeaders) > 0:
-		h.suppressOrSet("User-Agent", uAgent)
-		h.suppressOrSet("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,*/*;q=0.8")
-		h.suppressOrSet("Accept-Language", "en-US,en;q=0.5")
-		h.suppressOrSet("DNT", "1")
-		h.suppressOrSet("Connection", "keep-alive")
-		h.suppressOrSet("Upgrade-Insecure-Requests", "1")
-		h.suppressOrSet("Sec-Fetch-Dest", "document")
-		h.suppressOrSet("Sec-Fetch-Mode", "navigate")
-		h.suppressOrSet("Sec-Fetch-Site", "none")
-		h.suppressOrSet("Sec-Fetch-User", "?1")
-		h.suppressOrSet("Sec-GCP", "1")
-	default:
-		h.headerMap["User-Agent"] = []string{uAgent}
-		h.headerMap["Accept"] = []string{"text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,*/*;q=0.8"}
-		h.headerMap["Accept-Language"] = []string{"en-US,en;q=0.5"}
-		h.headerMap["DNT"] = []string{"1"}
-		h.headerMap["Connection"] = []string{"keep-alive"}
-		h.headerMap["Upgrade-Insecure-Requests"] = []string{"1"}
-		h.headerMap["Sec-Fetch-Dest"] = []string{"document"}
-		h.headerMap["Sec-Fetch-Mode"] = []string{"navigate"}
-		h.headerMap["Sec-Fetch-Site"] = []string{"none"}
-		h.headerMap["Sec-Fetch-User"] = []string{"?1"}
-		h.headerMap["Sec-GCP"] = []string{"1"}
-	}
-}
-
-func (h *headers) chrome() {
-	uAgent := h.chromeUA()
-	switch {
-	case h.customHeaders && len(h.suppressHeaders) > 0:
-		h.suppressOrSet("Connection", "keep-alive")
-		h.suppressOrSet("Cache-Control", "max-age=0")
-		h.suppressOrSet("sec-ch-ua", `"Not A;Brand";v="99", "Chromium";v="99", "Google Chrome";v="99"`)
-		h.suppressOrSet("sec-ch-ua-mobile", "?0")
-		switch h.osys {
-		case "m":
-			h.suppressOrSet("sec-ch-ua-platform", "Macintosh")
-		case "l":
-			h.suppressOrSet("sec-ch-ua-platform", "Linux")
-		default:
-			h.suppressOrSet("sec-ch-ua-platform", "Windows")
-		}
-		h.suppressOrSet("Upgrade-Insecure-Requests", "1")
-		h.suppressOrSet("User-Agent", uAgent)
-		h.suppressOrSet("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,*/*;q=0.8")
-		h.suppressOrSet("Sec-Fetch-Site", "none")
-		h.suppressOrSet("Sec-Fetch-Mode", "navigate")
-		h.suppressOrSet("Sec-Fetch-User", "?1")
-		h.suppressOrSet("Sec-Fetch-Dest", "document")
-		h.suppressOrSet("Accept-Language", "en-US,en;q=0.5")
-	case h.customHeaders:
-		h.headerMap.add("Connection", "keep-alive")
-		h.headerMap.add("Cache-Control", "max-age=0")
-		h.headerMap.add("sec-ch-ua", `"Not A;Brand";v="99", "Chromium";v="99", "Google Chrome";v="99"`)
-		h.headerMap.add("sec-ch-ua-mobile", "?0")
-		switch h.osys {
-		case "m":
-			h.headerMap.add("sec-ch-ua-platform", "Macintosh")
-		case "l":
-			h.headerMap.add("sec-ch-ua-platform", "Linux")
-		default:
-			h.headerMap.add("sec-ch-ua-platform", "Windows")
+// applyHeaders sets the headers in hdr on req that it doesn't already
+// have, as described for Apply.
+func applyHeaders(req *http.Request, hdr http.Header) {
+	if req.Header == nil {
+		req.Header = http.Header{}
+	}
+	has := map[string]bool{}
+	for k := range req.Header {
+		has[strings.ToLower(k)] = true
+	}
+	for k, vs := range hdr {
+		lk := strings.ToLower(k)
+		if has[lk] || len(vs) == 0 {
+			continue
 		}
-		h.headerMap.add("Upgrade-Insecure-Requests", "1")
-		h.headerMap.add("User-Agent", uAgent)
-		h.headerMap.add("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,*/*;q=0.8")
-		h.headerMap.add("Sec-Fetch-Site", "none")
-		h.headerMap.add("Sec-Fetch-Mode", "navigate")
-		h.headerMap.add("Sec-Fetch-User", "?1")
-		h.headerMap.add("Sec-Fetch-Dest", "document")
-		h.headerMap.add("Accept-Language", "en-US,en;q=0.5")
-	case len(h.suppressHeaders) > 0:
-		h.suppressOrSet("Connection", "keep-alive")
-		h.suppressOrSet("Cache-Control", "max-age=0")
-		h.suppressOrSet("sec-ch-ua", `"Not A;Brand";v="99", "Chromium";v="99", "Google Chrome";v="99"`)
-		h.suppressOrSet("sec-ch-ua-mobile", "?0")
-		switch h.osys {
-		case "m":
-			h.suppressOrSet("sec-ch-ua-platform", "Macintosh")
-		case "l":
-			h.suppressOrSet("sec-ch-ua-platform", "Linux")
-		default:
-			h.suppressOrSet("sec-ch-ua-platform", "Windows")
+		if lk == "host" {
+			if req.Host == "" || (req.URL != nil && req.Host == req.URL.Host) {
+				req.Host = vs[0]
+			}
+			continue
 		}
-		h.suppressOrSet("Upgrade-Insecure-Requests", "1")
-		h.suppressOrSet("User-Agent", uAgent)
-		h.suppressOrSet("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,*/*;q=0.8")
-		h.suppressOrSet("Sec-Fetch-Site", "none")
-		h.suppressOrSet("Sec-Fetch-Mode", "navigate")
-		h.suppressOrSet("Sec-Fetch-User", "?1")
-		h.suppressOrSet("Sec-Fetch-Dest", "document")
-		h.suppressOrSet("Accept-Language", "en-US,en;q=0.5")
-	default:
-		h.headerMap["Connection"] = []string{"keep-alive"}
-		h.headerMap["Cache-Control"] = []string{"max-age=0"}
-		h.headerMap["sec-ch-ua"] = []string{`"Not A;Brand";v="99", "Chromium";v="99", "Google Chrome";v="99"`}
-		h.headerMap["sec-ch-ua-mobile"] = []string{"?0"}
-		switch h.osys {
-		case "m":
-			h.headerMap["sec-ch-ua-platform"] = []string{"Macintosh"}
-		case "l":
-			h.headerMap["sec-ch-ua-platform"] = []string{"Linux"}
-		default:
-			h.headerMap["sec-ch-ua-platform"] = []string{"Windows"}
+		req.Header[k] = append([]string(nil), vs...)
+	}
+}
+
+// generate fills hm in from p and returns what it chose.
+func (h *headers) generate(hm headerMap, p *Profile, osys string) Choice {
+	c := h.choose(p, osys)
+	h.build(hm, p, c, h.requestType, h.nav)
+	return c
+}
+
+// choose picks the ua and locale for a set of headers from p, unless
+// WithChoice already did.
+func (h *headers) choose(p *Profile, osys string) Choice {
+	c := Choice{Profile: p.Name, OS: p.uaOS(osys)}
+	if rp, ua, ok := h.rawUserAgent(); ok && rp == p {
+		c.UserAgent, c.OS = ua, ua.OS
+	} else if h.choice != nil && h.choice.UserAgent.Value != "" {
+		c.UserAgent = h.choice.UserAgent
+	} else {
+		c.UserAgent = h.pickUserAgent(p, osys)
+	}
+	if h.choice != nil && h.choice.Locale != "" {
+		c.Locale = h.choice.Locale
+	} else {
+		c.Locale = h.pickLocale()
+	}
+	return c
+}
+
+// build fills hm in from p's template for the request type kind, made
+// as part of the navigation nav, with the ua and locale in c.
+func (h *headers) build(hm headerMap, p *Profile, c Choice, kind string, nav navigation) {
+	ua := c.UserAgent
+	var hints map[string]string
+	if p.ClientHints != nil {
+		hints = p.ClientHints(ua)
+	}
+	policy := p.ReferrerPolicy
+	if policy == "" {
+		policy = defaultReferrerPolicy
+	}
+	high := highEntropy(p, ua, h.hintStore.Hints(nav.to))
+	tmpl := p.template(kind)
+	mode := fieldValue(tmpl, "Sec-Fetch-Mode")
+	for _, f := range tmpl {
+		v := f.Value
+		if v == "" {
+			v = fieldValue(p.Template, f.Name)
 		}
-		h.headerMap["Upgrade-Insecure-Requests"] = []string{"1"}
-		h.headerMap["User-Agent"] = []string{uAgent}
-		h.headerMap["Accept"] = []string{"text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,*/*;q=0.8"}
-		h.headerMap["Sec-Fetch-Site"] = []string{"none"}
-		h.headerMap["Sec-Fetch-Mode"] = []string{"navigate"}
-		h.headerMap["Sec-Fetch-User"] = []string{"?1"}
-		h.headerMap["Sec-Fetch-Dest"] = []string{"document"}
-		h.headerMap["Accept-Language"] = []string{"en-US,en;q=0.5"}
-	}
-
-}
-
-func (h *headers) ffUA() string {
-	var userAgents []string
-	switch h.osys {
-	case "m":
-		userAgents = []string{
-			"Mozilla/5.0 (Macintosh; Intel Mac OS X 13.13.1; rv:110.0) Gecko/20100101 Firefox/110.0",
-			"Mozilla/5.0 (Macintosh; Intel Mac OS X 13.13; rv:108.0) Gecko/20100101 Firefox/108.0",
-			"Mozilla/5.0 (Macintosh; Intel Mac OS X 13.13; rv:106.0) Gecko/20100101 Firefox/106.0",
-			"Mozilla/5.0 (Macintosh; Intel Mac OS X 11.7.6; rv:108.0) Gecko/20100101 Firefox/108.0",
-			"Mozilla/5.0 (Macintosh; Intel Mac OS X 11.1; rv:108.0) Gecko/20100101 Firefox/108.0",
-			"Mozilla/5.0 (Macintosh; Intel Mac OS X 11.1; rv:110.0) Gecko/20100101 Firefox/110.0",
-			"Mozilla/5.0 (Macintosh; Intel Mac OS X 10.15.7; rv:106.0) Gecko/20100101 Firefox/106.0",
-			"Mozilla/5.0 (Macintosh; Intel Mac OS X 12.6.5; rv:110.0) Gecko/20100101 Firefox/110.0",
-			"Mozilla/5.0 (Macintosh; Intel Mac OS X 12.1; rv:104.0) Gecko/20100101 Firefox/104.0",
-			"Mozilla/5.0 (Macintosh; Intel Mac OS X 10.15; rv:102.0) Gecko/20100101 Firefox/102.0",
+		if hv, ok := hints[f.Name]; ok {
+			v = hv
 		}
-	case "l":
-		userAgents = []string{
-			"Mozilla/5.0 (X11; Linux x86_64; rv:110.0) Gecko/20100101 Firefox/110.0",
-			"Mozilla/5.0 (X11; Linux x86_64; rv:109.0) Gecko/20100101 Firefox/109.0",
-			"Mozilla/5.0 (X11; Linux x86_64; rv:108.0) Gecko/20100101 Firefox/108.0",
-			"Mozilla/5.0 (X11; Linux x86_64; rv:107.0) Gecko/20100101 Firefox/107.0",
-			"Mozilla/5.0 (X11; Linux x86_64; rv:106.0) Gecko/20100101 Firefox/106.0",
-			"Mozilla/5.0 (X11; Linux x86_64; rv:105.0) Gecko/20100101 Firefox/105.0",
-			"Mozilla/5.0 (X11; Linux x86_64; rv:104.0) Gecko/20100101 Firefox/104.0",
-			"Mozilla/5.0 (X11; Linux x86_64; rv:103.0) Gecko/20100101 Firefox/103.0",
-			"Mozilla/5.0 (X11; Linux x86_64; rv:101.0) Gecko/20100101 Firefox/101.0",
-			"Mozilla/5.0 (X11; Linux x86_64; rv:93.0) Gecko/20100101 Firefox/93.0",
+		if hv, ok := high[strings.ToLower(f.Name)]; ok {
+			v = hv
 		}
-	default:
-		userAgents = []string{
-			"Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:110.0) Gecko/20100101 Firefox/110.0",
-			"Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:109.0) Gecko/20100101 Firefox/109.0",
-			"Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:108.0) Gecko/20100101 Firefox/108.0",
-			"Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:107.0) Gecko/20100101 Firefox/107.0",
-			"Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:106.0) Gecko/20100101 Firefox/106.0",
-			"Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:105.0) Gecko/20100101 Firefox/105.0",
-			"Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:104.0) Gecko/20100101 Firefox/104.0",
-			"Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:103.0) Gecko/20100101 Firefox/103.0",
-			"Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:102.0) Gecko/20100101 Firefox/102.0",
-			"Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:99.0) Gecko/20100101 Firefox/99.0",
+		if nv, ok := nav.apply(f.Name, mode, policy); ok {
+			v = nv
 		}
-	}
-	random := rand.Intn(len(userAgents))
-	return userAgents[random]
-}
-
-func (h *headers) chromeUA() string {
-	var userAgents []string
-	switch h.osys {
-	case "m":
-		userAgents = []string{
-			"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_14_6) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/108.0.0.0 Safari/537.36",
-			"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/108.0.0.0 Safari/537.36",
-			"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/100.0.4896.127 Safari/537.36",
-			"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/100.0.4692.56 Safari/537.36",
-			"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/100.0.4889.0 Safari/537.36",
-			"Mozilla/5.0 (Macintosh; Intel Mac OS X 13_13_1) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/110.0.0.0 Safari/537.36",
-			"Mozilla/5.0 (Macintosh; Intel Mac OS X 11_7_6) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/108.0.0.0 Safari/537.36",
-			"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/110.0.0.0 Safari/537.36",
-			"Mozilla/5.0 (Macintosh; Intel Mac OS X 12_6_5) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/108.0.0.0 Safari/537.36",
-			"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/106.0.0.0 Safari/537.36",
+		if f.Name == "User-Agent" {
+			v = ua.Value
 		}
-	case "l":
-		userAgents = []string{
-			"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/83.0.4103.106 Safari/537.36",
-			"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/108.0.0.0 Safari/537.36",
-			"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/110.0.0.0 Safari/537.36",
-			"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/109.0.0.0 Safari/537.36",
-			"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/106.0.0.0 Safari/537.36",
-			"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/105.0.0.0 Safari/537.36",
-			"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/108.0.0.0 Safari/537.36",
-			"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/112.0.5615.137 Safari/537.36",
-			"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/100.0.4692.56 Safari/537.36",
-			"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/100.0.4889.0 Safari/537.36",
+		if f.Name == "Accept-Language" && c.Locale != "" {
+			v = p.acceptLanguage(c.Locale)
 		}
-	default:
-		userAgents = []string{
-			"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/108.0.0.0 Safari/537.36",
-			"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/100.0.4896.127 Safari/537.36",
-			"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/101.0.4951.54 Safari/537.36",
-			"Mozilla/5.0 (Windows NT 10.0; WOW64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/99.0.4844.51 Safari/537.36",
-			"Mozilla/5.0 (Windows NT 10.0; WOW64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/99.0.4844.84 Safari/537.36",
-			"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/109.0.0.0 Safari/537.36",
-			"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/106.0.0.0 Safari/537.36",
-			"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/101.0.0.0 Safari/537.36",
-			"Mozilla/5.0 (Windows NT 10.0; WOW64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/110.0.0.0 Safari/537.36",
-			"Mozilla/5.0 (Windows NT 10.0; WOW64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/112.0.5615.137 Safari/537.36",
+		if v == "" || !p.sends(f.Name, ua) {
+			continue
 		}
+		h.set(hm, f.Name, v)
 	}
-	random := rand.Intn(len(userAgents))
-	return userAgents[random]
+	h.mergeRaw(hm)
+}
+
+func (h *headers) chrome() headerMap {
+	p, _ := h.lookup("chrome")
+	hm := h.headerMap.clone()
+	h.generate(hm, p, h.osys)
+	return hm
+}
+
+func (h *headers) firefox() headerMap {
+	p, _ := h.lookup("firefox")
+	hm := h.headerMap.clone()
+	h.generate(hm, p, h.osys)
+	return hm
+}
+
+func Headers() http.Header {
+	return NewHeaders().Headers()
 }
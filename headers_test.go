@@ -1,6 +1,11 @@
 package fuzzyHelpers
 
 import (
+	"math/rand"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
 )
 
@@ -16,9 +21,8 @@ func TestChrome(t *testing.T) {
 		}
 	})
 	t.Run("chrome() provides correct number of chrome headers", func(t *testing.T) {
-		want := 13
-		h.chrome()
-		got := len(h.headerMap)
+		want := 14
+		got := len(h.chrome())
 		if got != want {
 			t.Errorf("got %d want %d", got, want)
 		}
@@ -37,13 +41,21 @@ func TestFirefox(t *testing.T) {
 		}
 	})
 	t.Run("firefox() provides correct number of headers", func(t *testing.T) {
-		want := 11
-		h.firefox()
-		got := len(h.headerMap)
+		want := 12
+		got := len(h.firefox())
 		if got != want {
 			t.Errorf("got %d want %d", got, want)
 		}
 	})
+	t.Run("firefox() sends Sec-GPC", func(t *testing.T) {
+		hm := h.firefox()
+		if got := headerValue(http.Header(hm), "Sec-GPC"); got != "1" {
+			t.Errorf("got Sec-GPC %q want 1", got)
+		}
+		if headerValue(http.Header(hm), "Sec-GCP") != "" {
+			t.Error("sent Sec-GCP, which no browser does")
+		}
+	})
 }
 
 func TestWithURL(t *testing.T) {
@@ -76,9 +88,8 @@ func TestWithOSInHeader(t *testing.T) {
 		WithOS("m"),
 	)
 	// check chrome for sec-ch-ua-platform header
-	h.chrome()
-	want := "Macintosh"
-	got := h.headerMap["sec-ch-ua-platform"][0]
+	want := `"macOS"`
+	got := h.chrome()["sec-ch-ua-platform"][0]
 	if got != want {
 		t.Errorf("got %s want %s", got, want)
 	}
@@ -90,9 +101,8 @@ func TestWithOSDefaultWindows(t *testing.T) {
 		WithOS("foo"),
 	)
 	// check chrome for sec-ch-ua-platform header
-	h.chrome()
-	want := "Windows"
-	got := h.headerMap["sec-ch-ua-platform"][0]
+	want := `"Windows"`
+	got := h.chrome()["sec-ch-ua-platform"][0]
 	if got != want {
 		t.Errorf("got %s want %s", got, want)
 	}
@@ -128,12 +138,28 @@ func TestBadOSInputUsesDefault(t *testing.T) {
 	}
 }
 
+func TestUnsupportedOSReportsFallback(t *testing.T) {
+	t.Parallel()
+	// chrome has no ios user agents, so the ua comes from windows.
+	h := NewHeaders(
+		ChromeOnly(true),
+		WithOS("i"),
+	)
+	headers, c := h.Generate()
+	if c.OS != "w" {
+		t.Errorf("choice os was %s for %s, wanted 'w'", c.OS, headers["User-Agent"][0])
+	}
+	if got := NewIdentity(ChromeOnly(true), WithOS("i")).OS(); got != "w" {
+		t.Errorf("identity os was %s, wanted 'w'", got)
+	}
+}
+
 func TestWithOSInUA(t *testing.T) {
 	t.Parallel()
 	h := NewHeaders(
 		WithOS("m"),
 	)
-	h.chrome()
+	hm := h.chrome()
 	ua := []string{
 		"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_14_6) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/108.0.0.0 Safari/537.36",
 		"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/108.0.0.0 Safari/537.36",
@@ -146,8 +172,8 @@ func TestWithOSInUA(t *testing.T) {
 		"Mozilla/5.0 (Macintosh; Intel Mac OS X 12_6_5) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/108.0.0.0 Safari/537.36",
 		"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/106.0.0.0 Safari/537.36",
 	}
-	if !assertCorrectUA(t, h.headerMap["User-Agent"][0], ua) {
-		t.Errorf("wanted %s to be a Macintosh ua", h.headerMap["User-Agent"][0])
+	if !assertCorrectUA(t, hm["User-Agent"][0], ua) {
+		t.Errorf("wanted %s to be a Macintosh ua", hm["User-Agent"][0])
 	}
 }
 
@@ -161,6 +187,17 @@ func assertCorrectUA(t *testing.T, ua string, possible []string) bool {
 	return false
 }
 
+// headerCounts is how many headers each built in profile sends for a
+// desktop page load.
+var headerCounts = map[string]int{
+	"brave":   14,
+	"chrome":  14,
+	"edge":    13,
+	"firefox": 12,
+	"opera":   13,
+	"safari":  8,
+}
+
 func TestHeaders(t *testing.T) {
 	t.Parallel()
 	headers := Headers()
@@ -175,8 +212,11 @@ func TestHeaders(t *testing.T) {
 		}
 	})
 	t.Run("correct number of headers", func(t *testing.T) {
-		if len(headers) != 11 && len(headers) != 13 {
-			t.Errorf("number of headers was %d, wanted 11 or 13", len(headers))
+		for name, want := range headerCounts {
+			got := len(NewHeaders(WithProfile(name), WithOS("m")).Headers())
+			if got != want {
+				t.Errorf("%s: number of headers was %d, wanted %d", name, got, want)
+			}
 		}
 	})
 }
@@ -203,9 +243,16 @@ func TestCustomHeaders(t *testing.T) {
 		}
 	})
 	t.Run("correct number of headers", func(t *testing.T) {
-		// 11 or 13 as default plus the Host header
-		if len(headers) != 12 && len(headers) != 14 {
-			t.Errorf("number of headers was %d, wanted 12 or 14", len(headers))
+		// a profile's defaults plus the Host header
+		for name, want := range headerCounts {
+			got := len(NewHeaders(
+				WithProfile(name),
+				WithOS("m"),
+				WithCustomHeaders("Host=example.com User-Agent=foobar"),
+			).Headers())
+			if got != want+1 {
+				t.Errorf("%s: number of headers was %d, wanted %d", name, got, want+1)
+			}
 		}
 	})
 }
@@ -269,3 +316,195 @@ func TestChromePriority(t *testing.T) {
 		t.Error("wanted 'sec-ch-ua-platform' header but got none")
 	}
 }
+
+func TestHeadersNoCrossContamination(t *testing.T) {
+	t.Parallel()
+	h := NewHeaders(
+		WithCustomHeaders("X-Custom=1"),
+	)
+	for i := 0; i < 200; i++ {
+		headers := h.Headers()
+		p := matchProfile(headers)
+		if p == nil {
+			t.Fatalf("no profile matches %v", headers)
+		}
+		for k := range headers {
+			if k == "X-Custom" {
+				continue
+			}
+			found := false
+			for _, f := range p.Template {
+				if f.Name == k {
+					found = true
+					break
+				}
+			}
+			if !found {
+				t.Fatalf("%s headers include %s from another profile", p.Name, k)
+			}
+		}
+	}
+	if len(h.headerMap) != 1 {
+		t.Errorf("generating headers changed the base set: %v", h.headerMap)
+	}
+}
+
+func TestHeadersConcurrent(t *testing.T) {
+	t.Parallel()
+	h := NewHeaders(
+		WithURL("https://example.com"),
+	)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				headers := h.Headers()
+				headers.Set("X-Mine", "1")
+				if headers.Get("Host") != "example.com" {
+					t.Errorf("got host %q", headers.Get("Host"))
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if _, ok := h.Headers()["X-Mine"]; ok {
+		t.Error("changes to a returned set leaked into the next one")
+	}
+}
+
+func TestWithSeed(t *testing.T) {
+	t.Parallel()
+	a := NewHeaders(WithOS("any"), WithSeed(42))
+	b := NewHeaders(WithSeed(42), WithOS("any"))
+	if a.osys != b.osys {
+		t.Fatalf("got os %s and %s for the same seed", a.osys, b.osys)
+	}
+	for i := 0; i < 20; i++ {
+		ha, ca := a.Generate()
+		hb, cb := b.Generate()
+		if ca != cb {
+			t.Fatalf("call %d: got choices %+v and %+v", i, ca, cb)
+		}
+		if !reflect.DeepEqual(ha, hb) {
+			t.Fatalf("call %d: got headers\n%v\n%v", i, ha, hb)
+		}
+	}
+}
+
+func TestWithRand(t *testing.T) {
+	t.Parallel()
+	a := NewHeaders(WithRand(rand.New(rand.NewSource(7))))
+	b := NewHeaders(WithSeed(7))
+	for i := 0; i < 10; i++ {
+		_, ca := a.Generate()
+		_, cb := b.Generate()
+		if ca != cb {
+			t.Fatalf("call %d: got choices %+v and %+v", i, ca, cb)
+		}
+	}
+}
+
+func TestWithChoice(t *testing.T) {
+	t.Parallel()
+	h := NewHeaders(WithOS("any"))
+	for i := 0; i < 20; i++ {
+		want, c := h.Generate()
+		if want.Get("User-Agent") != c.UserAgent.Value {
+			t.Fatalf("choice has ua %q, headers %q", c.UserAgent.Value, want.Get("User-Agent"))
+		}
+		got := NewHeaders(WithChoice(c)).Headers()
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("replaying %+v got\n%v\nwant\n%v", c, got, want)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	t.Parallel()
+	srv := newRawServer(t)
+	req, err := http.NewRequest("GET", srv.url(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("User-Agent", "mine")
+	req.Header["accept"] = []string{"text/plain"}
+	h := NewHeaders(WithProfile("chrome"), WithURL("https://example.com/"))
+	if c := h.Apply(req); c.Profile != "chrome" {
+		t.Errorf("got choice %+v", c)
+	}
+	if _, ok := req.Header["Host"]; ok {
+		t.Error("Host was left in req.Header")
+	}
+	if req.Host != "example.com" {
+		t.Errorf("got req.Host %q want example.com", req.Host)
+	}
+	if got := req.Header.Get("User-Agent"); got != "mine" {
+		t.Errorf("User-Agent was replaced with %s", got)
+	}
+	if _, ok := req.Header["Accept"]; ok {
+		t.Error("Accept was added next to the caller's accept")
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	srv.mu.Lock()
+	lines := srv.reqs[0]
+	srv.mu.Unlock()
+	wire := strings.Join(headerNames(lines), ",")
+	for _, name := range []string{"Host", "sec-ch-ua", "sec-ch-ua-platform", "User-Agent", "accept"} {
+		if !strings.Contains(","+wire+",", ","+name+",") {
+			t.Errorf("%s wasn't sent as is, got %s", name, wire)
+		}
+	}
+	for _, line := range lines {
+		if strings.HasPrefix(line, "Host:") && line != "Host: example.com" {
+			t.Errorf("got %s", line)
+		}
+	}
+}
+
+func TestApplyKeepsCallersHost(t *testing.T) {
+	t.Parallel()
+	h := NewHeaders(WithURL("https://example.com/"))
+	req, _ := http.NewRequest("GET", "https://target.test/", nil)
+	req.Host = "fuzz.test"
+	h.Apply(req)
+	if req.Host != "fuzz.test" {
+		t.Errorf("got req.Host %s want fuzz.test", req.Host)
+	}
+	req, _ = http.NewRequest("GET", "https://target.test/", nil)
+	req.Header.Set("Host", "header.test")
+	h.Apply(req)
+	if req.Host != "target.test" || req.Header.Get("Host") != "header.test" {
+		t.Errorf("got req.Host %s and Host header %s", req.Host, req.Header.Get("Host"))
+	}
+}
+
+func TestApplyUsesRequest(t *testing.T) {
+	t.Parallel()
+	s := NewClientHintStore()
+	s.Remember(mustURL(t, "https://target.test/"), http.Header{"Accept-Ch": {"Sec-CH-UA-Arch"}})
+	h := NewHeaders(WithProfile("chrome"), WithReferrer("https://other.test/page"), WithClientHints(s))
+	req, _ := http.NewRequest("post", "https://target.test/form", nil)
+	h.Apply(req)
+	if got := req.Header.Get("Origin"); got != "https://other.test" {
+		t.Errorf("got Origin %q", got)
+	}
+	if got := req.Header.Get("Sec-Fetch-Site"); got != "cross-site" {
+		t.Errorf("got Sec-Fetch-Site %q", got)
+	}
+	if got := headerValue(req.Header, "sec-ch-ua-arch"); got == "" {
+		t.Error("hints asked for by the request's origin weren't sent")
+	}
+
+	id := NewIdentity(WithProfile("firefox"))
+	req, _ = http.NewRequest("GET", "https://target.test/", nil)
+	id.Apply(req)
+	if got, want := req.Header.Get("User-Agent"), id.UserAgent().Value; got != want {
+		t.Errorf("got ua %s want %s", got, want)
+	}
+}
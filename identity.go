@@ -0,0 +1,173 @@
+package fuzzyHelpers
+
+import (
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// Identity is a single browser persona: one profile, ua, os and
+// language, plus the cookies that browser has collected. Its headers
+// stay the same for as long as it lives, unlike those from
+// (*headers).Headers, until Rotate swaps it for a new persona.
+//
+// Identity is an http.CookieJar, so it can be handed to a client (see
+// WithIdentity) and its cookies rotate along with everything else.
+type Identity struct {
+	gen *headers
+
+	mu     sync.RWMutex
+	choice Choice
+	header http.Header
+	jar    http.CookieJar
+	// page is the last page Navigate loaded.
+	page *url.URL
+}
+
+// NewIdentity creates a persona from the same options NewHeaders
+// takes. With WithOS("any") each rotation picks a new os as well.
+func NewIdentity(opts ...optionHeaders) *Identity {
+	id := &Identity{gen: NewHeaders(opts...)}
+	id.Rotate()
+	return id
+}
+
+// Rotate replaces the persona with a freshly chosen one and throws away
+// its cookies and history.
+func (id *Identity) Rotate() {
+	osys := id.gen.osys
+	if id.gen.anyOS {
+		osys = id.gen.randOS()
+	}
+	header, choice := id.gen.generateOn(osys)
+	// cookiejar.New never fails.
+	jar, _ := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+	id.mu.Lock()
+	id.choice, id.header, id.jar, id.page = choice, header, jar, nil
+	id.mu.Unlock()
+}
+
+// Headers returns a copy of the persona's headers.
+func (id *Identity) Headers() http.Header {
+	id.mu.RLock()
+	defer id.mu.RUnlock()
+	return id.header.Clone()
+}
+
+// RequestHeaders returns the persona's headers for a kind of request
+// other than a page load, as with WithRequestType.
+func (id *Identity) RequestHeaders(kind string) http.Header {
+	choice := id.Choice()
+	p, ok := id.gen.lookup(choice.Profile)
+	if !ok {
+		return id.Headers()
+	}
+	hm := id.gen.headerMap.clone()
+	id.gen.build(hm, p, choice, strings.ToLower(kind), id.gen.nav)
+	return http.Header(hm)
+}
+
+// Navigate returns the persona's headers for a request of the given
+// kind to next, with Referer, Origin and Sec-Fetch-Site worked out from
+// the page it's on. Page loads (RequestNavigate, or "") then move it on
+// to next; other kinds of requests don't.
+func (id *Identity) Navigate(method, next, kind string) (http.Header, error) {
+	to, err := url.ParseRequestURI(next)
+	if err != nil {
+		return nil, err
+	}
+	return id.navigate(method, to, strings.ToLower(kind)), nil
+}
+
+// navigate implements Navigate for a parsed url.
+func (id *Identity) navigate(method string, to *url.URL, kind string) http.Header {
+	id.mu.Lock()
+	choice, from := id.choice, id.page
+	if kind == "" || kind == RequestNavigate {
+		id.page = to
+	}
+	id.mu.Unlock()
+
+	p, ok := id.gen.lookup(choice.Profile)
+	if !ok {
+		return id.Headers()
+	}
+	hm := id.gen.headerMap.clone()
+	if _, ok := hm["Host"]; ok {
+		hm["Host"] = []string{to.Host}
+	}
+	nav := navigation{from: from, to: to, method: strings.ToUpper(method)}
+	id.gen.build(hm, p, choice, kind, nav)
+	return http.Header(hm)
+}
+
+// Apply sets the persona's headers on req, the way (*headers).Apply
+// does. They're worked out for req's url and method as Navigate would,
+// for the WithRequestType kind of request (a page load by default).
+func (id *Identity) Apply(req *http.Request) {
+	if req.URL == nil {
+		applyHeaders(req, id.Headers())
+		return
+	}
+	method := req.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	applyHeaders(req, id.navigate(method, req.URL, id.gen.requestType))
+}
+
+// Choice reports the persona's profile, os and user agent.
+func (id *Identity) Choice() Choice {
+	id.mu.RLock()
+	defer id.mu.RUnlock()
+	return id.choice
+}
+
+// Profile is the name of the persona's browser profile.
+func (id *Identity) Profile() string {
+	return id.Choice().Profile
+}
+
+// UserAgent is the persona's user agent.
+func (id *Identity) UserAgent() UserAgent {
+	return id.Choice().UserAgent
+}
+
+// OS is the persona's os, one of the values WithOS accepts.
+func (id *Identity) OS() string {
+	return id.Choice().OS
+}
+
+// Language is the persona's Accept-Language.
+func (id *Identity) Language() string {
+	id.mu.RLock()
+	defer id.mu.RUnlock()
+	return id.header.Get("Accept-Language")
+}
+
+// Err returns the first error NewIdentity's options ran into, as
+// headers' Err does.
+func (id *Identity) Err() error {
+	return id.gen.Err()
+}
+
+// Jar returns the persona's current cookie jar.
+func (id *Identity) Jar() http.CookieJar {
+	id.mu.RLock()
+	defer id.mu.RUnlock()
+	return id.jar
+}
+
+// SetCookies implements http.CookieJar.
+func (id *Identity) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	id.Jar().SetCookies(u, cookies)
+}
+
+// Cookies implements http.CookieJar.
+func (id *Identity) Cookies(u *url.URL) []*http.Cookie {
+	return id.Jar().Cookies(u)
+}
@@ -0,0 +1,87 @@
+package fuzzyHelpers
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestIdentityIsStable(t *testing.T) {
+	t.Parallel()
+	id := NewIdentity(WithOS("any"))
+	want := id.Headers()
+	for i := 0; i < 20; i++ {
+		if got := id.Headers(); !reflect.DeepEqual(got, want) {
+			t.Fatalf("got\n%v\nwant\n%v", got, want)
+		}
+	}
+	if got := want.Get("User-Agent"); got != id.UserAgent().Value {
+		t.Errorf("got ua %q want %q", got, id.UserAgent().Value)
+	}
+	if got := want.Get("Accept-Language"); got != id.Language() {
+		t.Errorf("got language %q want %q", got, id.Language())
+	}
+	p, ok := LookupProfile(id.Profile())
+	if !ok || !p.supports(id.OS()) {
+		t.Errorf("profile %q doesn't exist on os %q", id.Profile(), id.OS())
+	}
+
+	id.Headers().Set("User-Agent", "changed")
+	if id.Headers().Get("User-Agent") == "changed" {
+		t.Error("changing returned headers changed the identity")
+	}
+}
+
+func TestIdentityRotate(t *testing.T) {
+	t.Parallel()
+	id := NewIdentity(WithSeed(1), WithOS("any"))
+	first := id.Choice()
+	changed := false
+	for i := 0; i < 20 && !changed; i++ {
+		id.Rotate()
+		changed = id.Choice() != first
+	}
+	if !changed {
+		t.Error("rotating never changed the identity")
+	}
+}
+
+func TestIdentityCookies(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := r.Cookie("session"); err != nil {
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc"})
+			w.Write([]byte("new"))
+			return
+		}
+		w.Write([]byte("known"))
+	}))
+	defer ts.Close()
+
+	id := NewIdentity()
+	c := NewClient(WithIdentity(id))
+	visit := func() string {
+		t.Helper()
+		req, _ := http.NewRequest("GET", ts.URL, nil)
+		req.Header = id.Headers()
+		resp, err := c.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+	if got := visit(); got != "new" {
+		t.Fatalf("first visit got %q", got)
+	}
+	if got := visit(); got != "known" {
+		t.Errorf("second visit got %q, cookie wasn't kept", got)
+	}
+	id.Rotate()
+	if got := visit(); got != "new" {
+		t.Errorf("visit after rotating got %q, cookie survived", got)
+	}
+}
@@ -0,0 +1,302 @@
+package fuzzyHelpers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Issue is an inconsistency Lint found in a set of headers.
+type Issue struct {
+	// Header is the header at fault, or empty if it's the set as a
+	// whole.
+	Header  string
+	Problem string
+}
+
+func (i Issue) String() string {
+	if i.Header == "" {
+		return i.Problem
+	}
+	return i.Header + ": " + i.Problem
+}
+
+// Lint checks a set of headers for things a real browser wouldn't send
+// together: client hints that disagree with the User-Agent (or that a
+// browser without client hints sends at all), headers the ua's version
+// doesn't send yet, fetch metadata that contradicts itself and headers
+// set twice under different spellings. The browser is recognized from
+// the User-Agent, so headers captured from a real browser can be
+// checked as well as generated ones. Lint reports what it finds in a
+// stable order, and nothing for a consistent set.
+func Lint(h http.Header) []Issue {
+	l := &linter{h: h}
+	l.duplicates()
+	l.fetchMetadata()
+	l.browser()
+	return l.issues
+}
+
+type linter struct {
+	h      http.Header
+	issues []Issue
+}
+
+func (l *linter) report(header, format string, args ...interface{}) {
+	l.issues = append(l.issues, Issue{header, fmt.Sprintf(format, args...)})
+}
+
+// get is headerValue, except that it tells empty headers from missing
+// ones.
+func (l *linter) get(name string) (string, bool) {
+	for k, vs := range l.h {
+		if strings.EqualFold(k, name) && len(vs) > 0 {
+			return vs[0], true
+		}
+	}
+	return "", false
+}
+
+// duplicates reports headers set more than once under different
+// spellings, e.g. "sec-ch-ua" by WithCustomHeaders next to a generated
+// "Sec-Ch-Ua".
+func (l *linter) duplicates() {
+	byName := map[string][]string{}
+	for k := range l.h {
+		lk := strings.ToLower(k)
+		byName[lk] = append(byName[lk], k)
+	}
+	names := make([]string, 0, len(byName))
+	for lk, keys := range byName {
+		if len(keys) > 1 {
+			names = append(names, lk)
+		}
+	}
+	sort.Strings(names)
+	for _, lk := range names {
+		keys := byName[lk]
+		sort.Strings(keys)
+		l.report(keys[0], "set more than once, as %s", strings.Join(keys, " and "))
+	}
+}
+
+// fetchMetadata checks Sec-Fetch-* against each other and against
+// Referer and Origin.
+func (l *linter) fetchMetadata() {
+	site, hasSite := l.get("Sec-Fetch-Site")
+	mode, hasMode := l.get("Sec-Fetch-Mode")
+	dest, hasDest := l.get("Sec-Fetch-Dest")
+	user, hasUser := l.get("Sec-Fetch-User")
+	referer, hasReferer := l.get("Referer")
+	_, hasOrigin := l.get("Origin")
+
+	if hasUser && user != "?1" {
+		l.report("Sec-Fetch-User", "is %s, but browsers only ever send ?1", user)
+	}
+	if hasUser && hasMode && mode != "navigate" {
+		l.report("Sec-Fetch-User", "is only sent with navigations, but Sec-Fetch-Mode is %s", mode)
+	}
+	if hasMode && hasDest && mode == "navigate" {
+		switch dest {
+		case "document", "iframe", "frame", "embed", "object":
+		default:
+			l.report("Sec-Fetch-Dest", "is %s, which can't be navigated to", dest)
+		}
+	}
+	if hasMode && hasDest && mode != "navigate" && (dest == "document" || dest == "iframe") {
+		l.report("Sec-Fetch-Mode", "is %s, but Sec-Fetch-Dest %s is a navigation", mode, dest)
+	}
+	if hasSite && site == "none" {
+		if hasReferer {
+			l.report("Referer", "is sent with Sec-Fetch-Site none, which means no page started the request")
+		}
+		if hasOrigin {
+			l.report("Origin", "is sent with Sec-Fetch-Site none, which means no page started the request")
+		}
+	}
+	host, hasHost := l.get("Host")
+	if hasSite && site == "same-origin" && hasReferer && hasHost && referer != "" {
+		if u, err := url.Parse(referer); err == nil && u.Host != "" && !strings.EqualFold(u.Host, host) {
+			l.report("Sec-Fetch-Site", "is same-origin, but Referer is from %s and Host is %s", u.Host, host)
+		}
+	}
+}
+
+// identify works out which profile and ua record the User-Agent belongs
+// to: one of the registered profiles' own, or failing that whatever the
+// ua says about itself. brave and chrome share uas, so where several
+// profiles fit, the one whose brand is in sec-ch-ua wins.
+func (l *linter) identify(value string) (*Profile, UserAgent, bool) {
+	type candidate struct {
+		p  *Profile
+		ua UserAgent
+	}
+	var candidates []candidate
+	for _, name := range Profiles() {
+		p, _ := LookupProfile(name)
+		for _, ua := range p.UserAgents {
+			if ua.Value == value {
+				candidates = append(candidates, candidate{p, ua})
+				break
+			}
+		}
+	}
+	if browser, ua, ok := parseUserAgent(value); ok {
+		if p, ok := LookupProfile(browser); ok {
+			candidates = append(candidates, candidate{p, ua})
+		}
+		if browser == "chrome" {
+			if p, ok := LookupProfile("brave"); ok {
+				candidates = append(candidates, candidate{p, ua})
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, UserAgent{}, false
+	}
+	sent, _ := l.get("sec-ch-ua")
+	for _, c := range candidates {
+		if c.p.ClientHints == nil || ownBrand(c.p, c.ua, sent) {
+			return c.p, c.ua, true
+		}
+	}
+	return candidates[0].p, candidates[0].ua, true
+}
+
+// browser checks the headers that depend on which browser sent them.
+func (l *linter) browser() {
+	value, ok := l.get("User-Agent")
+	if !ok || value == "" {
+		l.report("User-Agent", "missing")
+		return
+	}
+	p, ua, ok := l.identify(value)
+	if !ok {
+		l.report("User-Agent", "doesn't belong to a known browser, so it can't be checked against the rest")
+		return
+	}
+	l.versions(p, ua)
+	if p.ClientHints == nil {
+		var hints []string
+		for k := range l.h {
+			if strings.HasPrefix(strings.ToLower(k), "sec-ch-") {
+				hints = append(hints, k)
+			}
+		}
+		sort.Strings(hints)
+		for _, k := range hints {
+			l.report(k, "%s doesn't send client hints", p.Name)
+		}
+		return
+	}
+	l.clientHints(p, ua)
+}
+
+// versions reports headers the ua's version of p doesn't send yet.
+func (l *linter) versions(p *Profile, ua UserAgent) {
+	names := make([]string, 0, len(p.Since))
+	for name := range p.Since {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, ok := l.get(name); ok && !p.sends(name, ua) {
+			l.report(name, "isn't sent by %s before version %d, but the ua is version %s", p.Name, p.Since[name], ua.Version)
+		}
+	}
+}
+
+// clientHints checks a chromium browser's client hints against its ua.
+func (l *linter) clientHints(p *Profile, ua UserAgent) {
+	want := p.ClientHints(ua)
+	var high map[string]string
+	if p.HighEntropyHints != nil {
+		high = p.HighEntropyHints(ua)
+	}
+	brands, hasBrands := l.get("sec-ch-ua")
+	if hasBrands {
+		l.brands("sec-ch-ua", brands, want["sec-ch-ua"])
+	} else {
+		for k := range l.h {
+			if strings.HasPrefix(strings.ToLower(k), "sec-ch-ua-") {
+				l.report("sec-ch-ua", "missing, though other client hints are sent")
+				break
+			}
+		}
+	}
+	for _, name := range []string{"sec-ch-ua-mobile", "sec-ch-ua-platform"} {
+		if v, ok := l.get(name); ok && v != want[name] {
+			l.report(name, "is %s, but the ua is on %s", v, platformHint(ua.OS))
+		}
+	}
+	if v, ok := l.get("sec-ch-ua-full-version"); ok {
+		if got, _ := strconv.Unquote(v); major(got) != major(ua.Version) {
+			l.report("sec-ch-ua-full-version", "is %s, but the ua is version %d", v, major(ua.Version))
+		}
+	}
+	if v, ok := l.get("sec-ch-ua-full-version-list"); ok {
+		l.brands("sec-ch-ua-full-version-list", v, high["sec-ch-ua-full-version-list"])
+	}
+	if v, ok := l.get("sec-ch-ua-model"); ok && ua.OS != "a" && v != `""` {
+		l.report("sec-ch-ua-model", "is %s, but only mobiles report a model", v)
+	}
+	if ua.OS == "w" && high != nil {
+		for _, name := range []string{"sec-ch-ua-bitness", "sec-ch-ua-wow64"} {
+			if v, ok := l.get(name); ok && v != high[name] {
+				l.report(name, "is %s, but the ua implies %s", v, high[name])
+			}
+		}
+	}
+}
+
+var brandEntry = regexp.MustCompile(`"([^"]*)"\s*;\s*v\s*=\s*"([^"]*)"`)
+
+// parseBrands parses a sec-ch-ua style brand list into brand: version.
+func parseBrands(v string) map[string]string {
+	brands := map[string]string{}
+	for _, m := range brandEntry.FindAllStringSubmatch(v, -1) {
+		brands[m[1]] = m[2]
+	}
+	return brands
+}
+
+// ownBrand reports whether the brand list sent names p's own brand,
+// the one in p's list for ua that's neither Chromium nor GREASE.
+func ownBrand(p *Profile, ua UserAgent, sent string) bool {
+	want := brandEntry.FindAllStringSubmatch(p.ClientHints(ua)["sec-ch-ua"], -1)
+	for _, m := range want {
+		if m[1] != "Chromium" && !strings.Contains(m[1], "Brand") {
+			_, ok := parseBrands(sent)[m[1]]
+			return ok
+		}
+	}
+	return false
+}
+
+// brands compares the brands in a sent brand list to those in the one
+// the ua implies. Only major versions are compared, and the GREASE
+// brand, which is meant to vary, is left out.
+func (l *linter) brands(header, got, want string) {
+	sent := parseBrands(got)
+	expected := parseBrands(want)
+	names := make([]string, 0, len(expected))
+	for name := range expected {
+		if !strings.Contains(name, "Brand") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		v, ok := sent[name]
+		switch {
+		case !ok:
+			l.report(header, "doesn't list %q, which the ua implies", name)
+		case major(v) != major(expected[name]):
+			l.report(header, "lists %q version %s, but the ua is version %d", name, v, major(expected[name]))
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package fuzzyHelpers
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestLintGeneratedHeaders(t *testing.T) {
+	t.Parallel()
+	s := NewClientHintStore()
+	s.Remember(mustURL(t, "https://example.com/"), http.Header{"Accept-Ch": {
+		"Sec-CH-UA-Arch, Sec-CH-UA-Bitness, Sec-CH-UA-Full-Version, Sec-CH-UA-Full-Version-List, Sec-CH-UA-Model, Sec-CH-UA-Platform-Version, Sec-CH-UA-WoW64",
+	}})
+	kinds := []string{RequestNavigate, RequestFetch, RequestXHR, RequestImage, RequestIframe}
+	for _, name := range Profiles() {
+		for _, osys := range []string{"l", "m", "w", "a", "i"} {
+			for _, kind := range kinds {
+				h := NewHeaders(
+					WithProfile(name),
+					WithOS(osys),
+					WithRequestType(kind),
+					WithURL("https://example.com/a"),
+					WithReferrer("https://example.com/"),
+					WithClientHints(s),
+					WithSeed(1),
+				)
+				for i := 0; i < 5; i++ {
+					headers := h.Headers()
+					if issues := Lint(headers); len(issues) != 0 {
+						t.Errorf("%s %s %s: got %v for\n%v", name, osys, kind, issues, headers)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestLintCapturedHeaders(t *testing.T) {
+	t.Parallel()
+	chrome := http.Header{
+		"Host":               {"example.com"},
+		"Sec-Ch-Ua":          {`"Not_A Brand";v="8", "Chromium";v="120", "Google Chrome";v="120"`},
+		"Sec-Ch-Ua-Mobile":   {"?0"},
+		"Sec-Ch-Ua-Platform": {`"Windows"`},
+		"User-Agent":         {"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"},
+		"Sec-Fetch-Site":     {"none"},
+		"Sec-Fetch-Mode":     {"navigate"},
+		"Sec-Fetch-User":     {"?1"},
+		"Sec-Fetch-Dest":     {"document"},
+	}
+	if issues := Lint(chrome); len(issues) != 0 {
+		t.Errorf("chrome 120: got %v", issues)
+	}
+	brave := chrome.Clone()
+	brave["Sec-Ch-Ua"] = []string{`"Not_A Brand";v="8", "Chromium";v="120", "Brave";v="120"`}
+	brave["Sec-Gpc"] = []string{"1"}
+	if issues := Lint(brave); len(issues) != 0 {
+		t.Errorf("brave 120: got %v", issues)
+	}
+	firefox := http.Header{
+		"User-Agent":      {"Mozilla/5.0 (X11; Linux x86_64; rv:128.0) Gecko/20100101 Firefox/128.0"},
+		"Accept-Language": {"en-US,en;q=0.5"},
+		"Priority":        {"u=0, i"},
+	}
+	if issues := Lint(firefox); len(issues) != 0 {
+		t.Errorf("firefox 128: got %v", issues)
+	}
+}
+
+func TestLintIssues(t *testing.T) {
+	t.Parallel()
+	const chromeUA = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
+	tests := []struct {
+		name   string
+		header http.Header
+		want   []string
+	}{
+		{
+			"client hints from firefox",
+			http.Header{
+				"User-Agent": {"Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:109.0) Gecko/20100101 Firefox/110.0"},
+				"sec-ch-ua":  {`"Chromium";v="110"`},
+			},
+			[]string{"sec-ch-ua: firefox doesn't send client hints"},
+		},
+		{
+			"wrong platform",
+			http.Header{
+				"User-Agent":         {chromeUA},
+				"sec-ch-ua":          {`"Chromium";v="120", "Google Chrome";v="120"`},
+				"sec-ch-ua-mobile":   {"?1"},
+				"sec-ch-ua-platform": {`"Linux"`},
+			},
+			[]string{
+				`sec-ch-ua-mobile: is ?1, but the ua is on "Windows"`,
+				`sec-ch-ua-platform: is "Linux", but the ua is on "Windows"`,
+			},
+		},
+		{
+			"wrong brands",
+			http.Header{
+				"User-Agent":                  {chromeUA},
+				"sec-ch-ua":                   {`"Chromium";v="119", "Microsoft Edge";v="119"`},
+				"sec-ch-ua-full-version":      {`"121.0.6167.85"`},
+				"sec-ch-ua-full-version-list": {`"Chromium";v="120.0.6099.109", "Google Chrome";v="120.0.6099.109"`},
+			},
+			[]string{
+				`sec-ch-ua: lists "Chromium" version 119, but the ua is version 120`,
+				`sec-ch-ua: doesn't list "Google Chrome", which the ua implies`,
+				`sec-ch-ua-full-version: is "121.0.6167.85", but the ua is version 120`,
+			},
+		},
+		{
+			"64 bit hints from a WOW64 ua",
+			http.Header{
+				"User-Agent":        {"Mozilla/5.0 (Windows NT 10.0; WOW64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"},
+				"sec-ch-ua":         {`"Chromium";v="120", "Google Chrome";v="120"`},
+				"sec-ch-ua-bitness": {`"64"`},
+				"sec-ch-ua-wow64":   {"?0"},
+			},
+			[]string{
+				`sec-ch-ua-bitness: is "64", but the ua implies "32"`,
+				"sec-ch-ua-wow64: is ?0, but the ua implies ?1",
+			},
+		},
+		{
+			"hints without sec-ch-ua",
+			http.Header{
+				"User-Agent":       {chromeUA},
+				"sec-ch-ua-mobile": {"?0"},
+			},
+			[]string{"sec-ch-ua: missing, though other client hints are sent"},
+		},
+		{
+			"priority before chrome sent it",
+			http.Header{
+				"User-Agent": {"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/110.0.0.0 Safari/537.36"},
+				"sec-ch-ua":  {`"Chromium";v="110", "Google Chrome";v="110"`},
+				"Priority":   {"u=0, i"},
+			},
+			[]string{"Priority: isn't sent by chrome before version 124, but the ua is version 110.0.0.0"},
+		},
+		{
+			"duplicates",
+			http.Header{
+				"User-Agent": {"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.4 Safari/605.1.15"},
+				"Accept":     {"*/*"},
+				"accept":     {"text/html"},
+			},
+			[]string{"Accept: set more than once, as Accept and accept"},
+		},
+		{
+			"fetch metadata",
+			http.Header{
+				"User-Agent":     {"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.4 Safari/605.1.15"},
+				"Host":           {"example.com"},
+				"Referer":        {"https://other.test/"},
+				"Sec-Fetch-Site": {"none"},
+				"Sec-Fetch-Mode": {"cors"},
+				"Sec-Fetch-Dest": {"document"},
+				"Sec-Fetch-User": {"?0"},
+			},
+			[]string{
+				"Sec-Fetch-User: is ?0, but browsers only ever send ?1",
+				"Sec-Fetch-User: is only sent with navigations, but Sec-Fetch-Mode is cors",
+				"Sec-Fetch-Mode: is cors, but Sec-Fetch-Dest document is a navigation",
+				"Referer: is sent with Sec-Fetch-Site none, which means no page started the request",
+			},
+		},
+		{
+			"cross origin referer",
+			http.Header{
+				"User-Agent":     {"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.4 Safari/605.1.15"},
+				"Host":           {"example.com"},
+				"Referer":        {"https://other.test/"},
+				"Sec-Fetch-Site": {"same-origin"},
+			},
+			[]string{"Sec-Fetch-Site: is same-origin, but Referer is from other.test and Host is example.com"},
+		},
+		{
+			"no ua",
+			http.Header{"Accept": {"*/*"}},
+			[]string{"User-Agent: missing"},
+		},
+		{
+			"unknown ua",
+			http.Header{"User-Agent": {"curl/8.4.0"}},
+			[]string{"User-Agent: doesn't belong to a known browser, so it can't be checked against the rest"},
+		},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, issue := range Lint(tt.header) {
+			got = append(got, issue.String())
+		}
+		if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+			t.Errorf("%s: got\n%s\nwant\n%s", tt.name, strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+		}
+	}
+}
+
+func TestLintCustomHeaders(t *testing.T) {
+	t.Parallel()
+	h := NewHeaders(WithProfile("firefox"), WithCustomHeaders(`sec-ch-ua-platform="Linux"`))
+	issues := Lint(h.Headers())
+	if len(issues) != 1 || issues[0].Header != "sec-ch-ua-platform" {
+		t.Errorf("got %v", issues)
+	}
+}
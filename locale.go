@@ -0,0 +1,188 @@
+package fuzzyHelpers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// defaultLocales is the distribution WithRandomLocale uses when given
+// none, roughly the languages browsers are set to around the world.
+var defaultLocales = map[string]float64{
+	"en-US": 40,
+	"en-GB": 6,
+	"de-DE": 6,
+	"fr-FR": 5,
+	"es-ES": 4,
+	"es-MX": 3,
+	"pt-BR": 5,
+	"it-IT": 3,
+	"nl-NL": 2,
+	"pl-PL": 2,
+	"ru-RU": 5,
+	"tr-TR": 2,
+	"ja-JP": 5,
+	"ko-KR": 3,
+	"zh-CN": 6,
+	"zh-TW": 2,
+	"id-ID": 2,
+}
+
+// parseLocale turns a tag ("de-CH"), a list ("de-CH,fr-CH") or a
+// weighted list ("de-CH, fr;q=0.8") into canonical tags, most preferred
+// first. It returns nil if nothing in s parses.
+func parseLocale(s string) []string {
+	tags, _, err := language.ParseAcceptLanguage(s)
+	if err != nil {
+		return nil
+	}
+	var locale []string
+	seen := map[string]bool{}
+	for _, tag := range tags {
+		name := tag.String()
+		if name == "und" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		locale = append(locale, name)
+	}
+	return locale
+}
+
+// expandLocale adds each region specific tag's base language after the
+// last tag sharing that base, unless it's already there, the way
+// chromium does: en-US,en-GB,de becomes en-US,en-GB,en,de.
+func expandLocale(locale []string) []string {
+	base := func(tag string) string {
+		return strings.SplitN(tag, "-", 2)[0]
+	}
+	present := map[string]bool{}
+	for _, tag := range locale {
+		present[tag] = true
+	}
+	var out []string
+	for i, tag := range locale {
+		out = append(out, tag)
+		b := base(tag)
+		if b == tag || present[b] {
+			continue
+		}
+		if i+1 < len(locale) && base(locale[i+1]) == b {
+			continue
+		}
+		out = append(out, b)
+		present[b] = true
+	}
+	return out
+}
+
+// chromiumLanguages formats Accept-Language the way chromium (and
+// safari) do: q drops by 0.1 per language, down to 0.1.
+func chromiumLanguages(locale []string) string {
+	locale = expandLocale(locale)
+	parts := make([]string, len(locale))
+	for i, tag := range locale {
+		if i == 0 {
+			parts[i] = tag
+			continue
+		}
+		q := 10 - i
+		if q < 1 {
+			q = 1
+		}
+		parts[i] = fmt.Sprintf("%s;q=0.%d", tag, q)
+	}
+	return strings.Join(parts, ",")
+}
+
+// firefoxLanguages formats Accept-Language the way firefox does: q
+// drops by 1/n per language, with one decimal (two from ten languages
+// on).
+func firefoxLanguages(locale []string) string {
+	locale = expandLocale(locale)
+	n := len(locale)
+	parts := make([]string, n)
+	for i, tag := range locale {
+		if i == 0 {
+			parts[i] = tag
+			continue
+		}
+		// rounded half up, in hundredths, as firefox does.
+		q := int((1 - float64(i)/float64(n) + 0.005) * 100)
+		if n < 10 {
+			parts[i] = fmt.Sprintf("%s;q=0.%d", tag, (q+5)/10)
+		} else {
+			parts[i] = fmt.Sprintf("%s;q=0.%02d", tag, q)
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// acceptLanguage formats a locale (as a comma separated list of tags)
+// for p.
+func (p *Profile) acceptLanguage(locale string) string {
+	tags := strings.Split(locale, ",")
+	if p.AcceptLanguage != nil {
+		return p.AcceptLanguage(tags)
+	}
+	return chromiumLanguages(tags)
+}
+
+// WithLocale sets the languages the browser asks for: a BCP 47 tag
+// ("de-DE"), a list of them in order of preference ("de-CH,fr-CH") or a
+// weighted list ("de-CH, fr;q=0.8, en;q=0.5"). Accept-Language is then
+// written the way each browser writes it, with its own q-values.
+// Input that doesn't parse is ignored.
+func WithLocale(locale string) optionHeaders {
+	return func(h *headers) {
+		if tags := parseLocale(locale); tags != nil {
+			h.locale = strings.Join(tags, ",")
+			h.locales = nil
+		}
+	}
+}
+
+// WithRandomLocale picks a locale for every set of headers from a
+// weighted distribution, e.g. {"en-US": 3, "de-DE": 1}. Keys are
+// anything WithLocale accepts. A nil or empty map uses a built in
+// distribution of common browser languages.
+func WithRandomLocale(weights map[string]float64) optionHeaders {
+	return func(h *headers) {
+		if len(weights) == 0 {
+			weights = defaultLocales
+		}
+		h.locale = ""
+		h.locales = nil
+		for spec, w := range weights {
+			tags := parseLocale(spec)
+			if tags == nil || w <= 0 {
+				continue
+			}
+			h.locales = append(h.locales, weightedLocale{strings.Join(tags, ","), w})
+		}
+		// map order is random; sort so a seeded run repeats itself.
+		sort.Slice(h.locales, func(i, j int) bool {
+			return h.locales[i].locale < h.locales[j].locale
+		})
+	}
+}
+
+type weightedLocale struct {
+	locale string
+	weight float64
+}
+
+// pickLocale returns the locale for a new set of headers, "" meaning
+// the profile's own.
+func (h *headers) pickLocale() string {
+	if len(h.locales) == 0 {
+		return h.locale
+	}
+	weights := make([]float64, len(h.locales))
+	for i, l := range h.locales {
+		weights[i] = l.weight
+	}
+	return h.locales[h.pick(weights)].locale
+}
@@ -0,0 +1,90 @@
+package fuzzyHelpers
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAcceptLanguageFormats(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		locale            string
+		chromium, firefox string
+	}{
+		{"en-US", "en-US,en;q=0.9", "en-US,en;q=0.5"},
+		{"de", "de", "de"},
+		{"de-DE", "de-DE,de;q=0.9", "de-DE,de;q=0.5"},
+		{"de-DE,en-US", "de-DE,de;q=0.9,en-US;q=0.8,en;q=0.7", "de-DE,de;q=0.8,en-US;q=0.5,en;q=0.3"},
+		{"fr-CH, fr;q=0.9, en;q=0.8, de;q=0.7", "fr-CH,fr;q=0.9,en;q=0.8,de;q=0.7", "fr-CH,fr;q=0.8,en;q=0.5,de;q=0.3"},
+		{"en;q=0.2, ja-jp", "ja-JP,ja;q=0.9,en;q=0.8", "ja-JP,ja;q=0.7,en;q=0.3"},
+		{"en-US,en-GB", "en-US,en-GB;q=0.9,en;q=0.8", "en-US,en-GB;q=0.7,en;q=0.3"},
+	}
+	for _, tt := range tests {
+		tags := parseLocale(tt.locale)
+		if got := chromiumLanguages(tags); got != tt.chromium {
+			t.Errorf("chromium %q: got %s want %s", tt.locale, got, tt.chromium)
+		}
+		if got := firefoxLanguages(tags); got != tt.firefox {
+			t.Errorf("firefox %q: got %s want %s", tt.locale, got, tt.firefox)
+		}
+	}
+	many := firefoxLanguages([]string{"en", "de", "fr", "es", "it", "nl", "pl", "pt", "ru", "ja"})
+	if want := "en,de;q=0.90,fr;q=0.80,es;q=0.70,it;q=0.60,nl;q=0.50,pl;q=0.40,pt;q=0.30,ru;q=0.20,ja;q=0.10"; many != want {
+		t.Errorf("got %s want %s", many, want)
+	}
+}
+
+func TestWithLocale(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		profile, want string
+	}{
+		{"chrome", "pt-BR,pt;q=0.9"},
+		{"edge", "pt-BR,pt;q=0.9"},
+		{"safari", "pt-BR,pt;q=0.9"},
+		{"firefox", "pt-BR,pt;q=0.5"},
+	}
+	for _, tt := range tests {
+		for _, kind := range []string{RequestNavigate, RequestFetch} {
+			h := NewHeaders(WithProfile(tt.profile), WithOS("m"), WithLocale("pt-br"), WithRequestType(kind))
+			if got := h.Headers().Get("Accept-Language"); got != tt.want {
+				t.Errorf("%s %s: got %s want %s", tt.profile, kind, got, tt.want)
+			}
+		}
+	}
+	h := NewHeaders(WithProfile("chrome"), WithLocale("!!"))
+	if got := h.Headers().Get("Accept-Language"); got != "en-US,en;q=0.9" {
+		t.Errorf("bad locale: got %s want the default", got)
+	}
+}
+
+func TestWithRandomLocale(t *testing.T) {
+	t.Parallel()
+	h := NewHeaders(
+		WithProfile("chrome"),
+		WithSeed(3),
+		WithRandomLocale(map[string]float64{"de-DE": 1, "fr-FR": 1, "xx-invalid-!": 5, "es-ES": 0}),
+	)
+	seen := map[string]int{}
+	for i := 0; i < 200; i++ {
+		headers, c := h.Generate()
+		lang := headers.Get("Accept-Language")
+		seen[strings.SplitN(lang, ",", 2)[0]]++
+		if c.Locale == "" {
+			t.Fatal("choice has no locale")
+		}
+		if got := NewHeaders(WithChoice(c)).Headers().Get("Accept-Language"); got != lang {
+			t.Fatalf("replaying %+v got %s want %s", c, got, lang)
+		}
+	}
+	if len(seen) != 2 || seen["de-DE"] == 0 || seen["fr-FR"] == 0 {
+		t.Errorf("got locales %v, wanted de-DE and fr-FR", seen)
+	}
+
+	h = NewHeaders(WithProfile("firefox"), WithRandomLocale(nil))
+	for i := 0; i < 20; i++ {
+		if _, c := h.Generate(); c.Locale == "" {
+			t.Fatal("default distribution gave no locale")
+		}
+	}
+}
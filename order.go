@@ -0,0 +1,113 @@
+package fuzzyHelpers
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// defaultPseudoOrder is the pseudo-header order go's own http2 client
+// uses, for profiles that don't specify one.
+var defaultPseudoOrder = []string{":authority", ":method", ":path", ":scheme"}
+
+// matchProfile finds the registered profile a set of headers came from.
+// Profiles owning its User-Agent win, and ties (brave and chrome share
+// uas) go to the one whose template covers the most of its headers. It
+// returns nil if nothing overlaps.
+func matchProfile(h http.Header) *Profile {
+	ua := headerValue(h, "User-Agent")
+	var best *Profile
+	bestUA, bestScore := false, 0
+	for _, name := range Profiles() {
+		p, _ := LookupProfile(name)
+		owns := false
+		for _, u := range p.UserAgents {
+			if ua != "" && u.Value == ua {
+				owns = true
+				break
+			}
+		}
+		score := 0
+		for _, f := range p.Template {
+			if headerValue(h, f.Name) != "" {
+				score++
+			}
+		}
+		if score == 0 || (bestUA && !owns) {
+			continue
+		}
+		if (owns && !bestUA) || score > bestScore {
+			best, bestUA, bestScore = p, owns, score
+		}
+	}
+	return best
+}
+
+// headerValue is a case-insensitive h.Get that also finds keys that
+// were set without being canonicalized (e.g. "sec-ch-ua").
+func headerValue(h http.Header, name string) string {
+	if v := h.Get(name); v != "" {
+		return v
+	}
+	for k, vs := range h {
+		if strings.EqualFold(k, name) && len(vs) > 0 {
+			return vs[0]
+		}
+	}
+	return ""
+}
+
+// orderedFields flattens h into fields following p's template for the
+// kind of request h is for, using the template's spelling of each name.
+// Headers the template doesn't mention come afterwards, sorted and
+// spelled as they were set. Host is written with the given value, where
+// the template puts it or else first.
+func orderedFields(p *Profile, h http.Header, host string) []HeaderField {
+	byName := map[string][]string{}
+	for k := range h {
+		lk := strings.ToLower(k)
+		byName[lk] = append(byName[lk], k)
+	}
+	delete(byName, "host")
+	fields := []HeaderField{{"Host", host}}
+	add := func(name, lk string) {
+		keys := byName[lk]
+		sort.Strings(keys)
+		for _, k := range keys {
+			n := name
+			if n == "" {
+				n = k
+			}
+			for _, v := range h[k] {
+				fields = append(fields, HeaderField{n, v})
+			}
+		}
+		delete(byName, lk)
+	}
+	if p != nil {
+		for _, f := range p.requestTemplate(h) {
+			if strings.EqualFold(f.Name, "host") {
+				fields = append(fields[1:], HeaderField{f.Name, host})
+				continue
+			}
+			add(f.Name, strings.ToLower(f.Name))
+		}
+	}
+	rest := make([]string, 0, len(byName))
+	for lk := range byName {
+		rest = append(rest, lk)
+	}
+	sort.Strings(rest)
+	for _, lk := range rest {
+		add("", lk)
+	}
+	return fields
+}
+
+// pseudoOrder returns p's http2 pseudo-header order.
+func pseudoOrder(p *Profile) []string {
+	if p == nil || len(p.PseudoHeaderOrder) == 0 {
+		return defaultPseudoOrder
+	}
+	return p.PseudoHeaderOrder
+}
@@ -0,0 +1,187 @@
+package fuzzyHelpers
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// HeaderField is a single header in a profile's ordered template.
+type HeaderField struct {
+	Name  string
+	Value string
+}
+
+// UserAgent is a user agent string along with the browser build it
+// belongs to. Client hints are derived from these fields rather than by
+// parsing Value, so a record should describe Value exactly.
+type UserAgent struct {
+	Value string
+	// OS is one of "l", "m", "w", "a", "i".
+	OS string
+	// Version is the browser's full version, e.g. "110.0.5481.177".
+	Version string
+	// Chromium is the full chromium version for chromium based browsers
+	// that version themselves separately (edge, opera). Defaults to
+	// Version when empty.
+	Chromium string
+}
+
+// Profile describes the headers a browser sends. Template holds the
+// headers in the order the browser emits them. User-Agent is filled in
+// from UserAgents. Values returned by ClientHints take precedence over
+// the template, and fields left empty by both are not sent. ClientHints
+// may return hints that aren't in the template (e.g. high entropy ones);
+// those are only used when the template asks for them.
+type Profile struct {
+	Name        string
+	Template    []HeaderField
+	UserAgents  []UserAgent
+	ClientHints func(ua UserAgent) map[string]string
+	// HighEntropyHints returns the client hints the browser only sends
+	// to origins that asked for them with Accept-CH (see
+	// ClientHintStore). Like ClientHints, they're only sent where the
+	// template has a field for them.
+	HighEntropyHints func(ua UserAgent) map[string]string
+	// PseudoHeaderOrder is the order of the http2 pseudo-headers
+	// (":method", ":authority", ":scheme", ":path").
+	PseudoHeaderOrder []string
+	// TLS is the browser's ClientHello, used by WithTLSFingerprint.
+	TLS *TLSFingerprint
+	// HTTP2 is how the browser opens http2 connections, used by
+	// WithHTTP2Fingerprint.
+	HTTP2 *HTTP2Fingerprint
+	// RequestTemplates are the templates for requests other than page
+	// loads, keyed by request type (RequestFetch, RequestImage, ...).
+	// Empty values are taken from Template, so e.g. Accept-Language
+	// only needs setting once.
+	RequestTemplates map[string][]HeaderField
+	// Since holds, for headers only newer versions send, the first
+	// major version that does (the chromium version, for chromium
+	// based browsers). Older user agents leave them out.
+	Since map[string]int
+	// AcceptLanguage formats Accept-Language for a list of language
+	// tags, most preferred first, when a locale is chosen (see
+	// WithLocale). It defaults to chromium's format.
+	AcceptLanguage func(tags []string) string
+	// ReferrerPolicy is the browser's default referrer policy, used to
+	// derive Referer and Origin (see WithReferrer). It defaults to
+	// "strict-origin-when-cross-origin".
+	ReferrerPolicy string
+
+	byOS map[string][]UserAgent
+}
+
+var (
+	profileMu sync.RWMutex
+	profiles  = map[string]*Profile{}
+)
+
+// RegisterProfile makes p available to NewHeaders under its (lowercased)
+// name. Registering a name that already exists replaces that profile,
+// which lets callers swap out the built-in chrome and firefox profiles.
+func RegisterProfile(p *Profile) error {
+	if p == nil {
+		return errors.New("profile is nil")
+	}
+	name := strings.ToLower(strings.TrimSpace(p.Name))
+	if name == "" {
+		return errors.New("profile has no name")
+	}
+	if len(p.Template) == 0 {
+		return errors.New("profile " + name + " has no header template")
+	}
+	cp := *p
+	cp.Name = name
+	cp.Template = append([]HeaderField(nil), p.Template...)
+	cp.PseudoHeaderOrder = append([]string(nil), p.PseudoHeaderOrder...)
+	cp.RequestTemplates = map[string][]HeaderField{}
+	for kind, tmpl := range p.RequestTemplates {
+		cp.RequestTemplates[strings.ToLower(kind)] = append([]HeaderField(nil), tmpl...)
+	}
+	cp.Since = map[string]int{}
+	for name, v := range p.Since {
+		cp.Since[name] = v
+	}
+	cp.setUserAgents(p.UserAgents)
+	if len(cp.UserAgents) == 0 {
+		return errors.New("profile " + name + " has no user agents")
+	}
+	profileMu.Lock()
+	profiles[name] = &cp
+	profileMu.Unlock()
+	return nil
+}
+
+// LookupProfile returns the registered profile with the given name.
+func LookupProfile(name string) (*Profile, bool) {
+	profileMu.RLock()
+	defer profileMu.RUnlock()
+	p, ok := profiles[strings.ToLower(name)]
+	return p, ok
+}
+
+// Profiles returns the names of all registered profiles, sorted.
+func Profiles() []string {
+	profileMu.RLock()
+	defer profileMu.RUnlock()
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func mustRegister(p *Profile) {
+	if err := RegisterProfile(p); err != nil {
+		panic(err)
+	}
+}
+
+// setUserAgents replaces p's user agents with uas, leaving out empty
+// ones.
+func (p *Profile) setUserAgents(uas []UserAgent) {
+	p.UserAgents = nil
+	p.byOS = map[string][]UserAgent{}
+	for _, ua := range uas {
+		if ua.Value == "" {
+			continue
+		}
+		ua.OS = strings.ToLower(ua.OS)
+		p.UserAgents = append(p.UserAgents, ua)
+		p.byOS[ua.OS] = append(p.byOS[ua.OS], ua)
+	}
+}
+
+// supports reports whether p has user agents for osys.
+func (p *Profile) supports(osys string) bool {
+	_, ok := p.byOS[osys]
+	return ok
+}
+
+// fallbackOS is the order in which userAgent tries other operating
+// systems when a profile doesn't support the one asked for.
+var fallbackOS = []string{"w", "m", "l", "a", "i"}
+
+// userAgent picks a ua for osys (see uaOS).
+func (p *Profile) userAgent(osys string, intn func(int) int) UserAgent {
+	uas := p.byOS[p.uaOS(osys)]
+	return uas[intn(len(uas))]
+}
+
+// uaOS returns the os p's ua for osys comes from: osys itself, or if p
+// doesn't support it the first os in fallbackOS it does, and failing
+// that whatever it does support.
+func (p *Profile) uaOS(osys string) string {
+	if p.supports(osys) {
+		return osys
+	}
+	for _, o := range fallbackOS {
+		if p.supports(o) {
+			return o
+		}
+	}
+	return p.UserAgents[0].OS
+}
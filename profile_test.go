@@ -0,0 +1,235 @@
+package fuzzyHelpers
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var chromeMajor = regexp.MustCompile(`Chrome/(\d+)`)
+
+// registerTestProfile registers p and removes it once the test is done.
+// tests using it shouldn't call t.Parallel, otherwise the profile could
+// leak into the random choice made by other tests.
+func registerTestProfile(t *testing.T, p *Profile) {
+	t.Helper()
+	if err := RegisterProfile(p); err != nil {
+		t.Fatalf("unable to register profile: %v", err)
+	}
+	t.Cleanup(func() {
+		profileMu.Lock()
+		delete(profiles, p.Name)
+		profileMu.Unlock()
+	})
+}
+
+func TestBuiltinProfiles(t *testing.T) {
+	t.Parallel()
+	for _, name := range []string{"chrome", "firefox"} {
+		if _, ok := LookupProfile(name); !ok {
+			t.Errorf("wanted %s profile to be registered", name)
+		}
+	}
+}
+
+func TestRegisterProfile(t *testing.T) {
+	registerTestProfile(t, &Profile{
+		Name: "curl",
+		Template: []HeaderField{
+			{"User-Agent", ""},
+			{"Accept", "*/*"},
+		},
+		UserAgents: []UserAgent{
+			{Value: "curl/7.88.1", OS: "l", Version: "7.88.1"},
+		},
+	})
+	h := NewHeaders(
+		WithProfile("Curl"),
+	)
+	headers := h.Headers()
+	t.Run("user agent falls back to supported os", func(t *testing.T) {
+		if got := headers["User-Agent"][0]; got != "curl/7.88.1" {
+			t.Errorf("got %s want %q", got, "curl/7.88.1")
+		}
+	})
+	t.Run("correct number of headers", func(t *testing.T) {
+		if len(headers) != 2 {
+			t.Errorf("number of headers was %d, wanted 2", len(headers))
+		}
+	})
+}
+
+func TestRegisterBadProfile(t *testing.T) {
+	t.Parallel()
+	tests := map[string]*Profile{
+		"nil":      nil,
+		"no name":  {Template: []HeaderField{{"Accept", "*/*"}}, UserAgents: []UserAgent{{Value: "foo", OS: "w"}}},
+		"no tmpl":  {Name: "foo", UserAgents: []UserAgent{{Value: "foo", OS: "w"}}},
+		"no uas":   {Name: "foo", Template: []HeaderField{{"Accept", "*/*"}}},
+		"empty ua": {Name: "foo", Template: []HeaderField{{"Accept", "*/*"}}, UserAgents: []UserAgent{{OS: "w"}}},
+	}
+	for name, p := range tests {
+		if err := RegisterProfile(p); err == nil {
+			t.Errorf("%s: wanted error, got nil", name)
+		}
+	}
+}
+
+func TestUnknownProfileFallsBack(t *testing.T) {
+	t.Parallel()
+	h := NewHeaders(
+		WithProfile("netscape"),
+	)
+	if len(h.Headers()) == 0 {
+		t.Error("wanted headers, got none")
+	}
+}
+
+func TestBrowserProfiles(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		profile  string
+		osys     string
+		brand    string
+		platform string
+	}{
+		{"edge", "w", `"Microsoft Edge";v="`, `"Windows"`},
+		{"opera", "m", `"Opera";v="`, `"macOS"`},
+		{"brave", "l", `"Brave";v="`, `"Linux"`},
+	}
+	for _, tt := range tests {
+		h := NewHeaders(
+			WithProfile(tt.profile),
+			WithOS(tt.osys),
+		)
+		headers := h.Headers()
+		ua := headers["User-Agent"][0]
+		brands := headers["sec-ch-ua"][0]
+		if !strings.Contains(brands, tt.brand) {
+			t.Errorf("%s: wanted %s in sec-ch-ua, got %s", tt.profile, tt.brand, brands)
+		}
+		chromium := fmt.Sprintf(`"Chromium";v="%s"`, chromeMajor.FindStringSubmatch(ua)[1])
+		if !strings.Contains(brands, chromium) {
+			t.Errorf("%s: wanted %s in sec-ch-ua for ua %s, got %s", tt.profile, chromium, ua, brands)
+		}
+		if got := headers["sec-ch-ua-platform"][0]; got != tt.platform {
+			t.Errorf("%s: got platform %s want %s", tt.profile, got, tt.platform)
+		}
+	}
+}
+
+func TestSafariIsAppleOnly(t *testing.T) {
+	t.Parallel()
+	t.Run("windows request falls back to a mac ua", func(t *testing.T) {
+		h := NewHeaders(
+			WithProfile("safari"),
+			WithOS("w"),
+		)
+		ua := h.Headers()["User-Agent"][0]
+		if !strings.Contains(ua, "Macintosh") {
+			t.Errorf("wanted a mac ua, got %s", ua)
+		}
+	})
+	t.Run("never chosen at random for windows", func(t *testing.T) {
+		for i := 0; i < 50; i++ {
+			h := NewHeaders(
+				WithOS("w"),
+			)
+			ua := h.Headers()["User-Agent"][0]
+			if strings.Contains(ua, "Version/") {
+				t.Fatalf("got safari ua %s for windows", ua)
+			}
+		}
+	})
+}
+
+func TestMobileProfiles(t *testing.T) {
+	t.Parallel()
+	t.Run("android chrome", func(t *testing.T) {
+		h := NewHeaders(
+			ChromeOnly(true),
+			WithOS("a"),
+		)
+		headers := h.Headers()
+		ua := headers["User-Agent"][0]
+		if !strings.Contains(ua, "Android") || !strings.Contains(ua, "Mobile") {
+			t.Errorf("wanted a mobile android ua, got %s", ua)
+		}
+		if got := headers["sec-ch-ua-mobile"][0]; got != "?1" {
+			t.Errorf("got sec-ch-ua-mobile %s want ?1", got)
+		}
+		if got := headers["sec-ch-ua-platform"][0]; got != `"Android"` {
+			t.Errorf("got sec-ch-ua-platform %s want %q", got, `"Android"`)
+		}
+		model := headers["sec-ch-ua-model"][0]
+		if model == `""` || !strings.Contains(ua, strings.Trim(model, `"`)+")") {
+			t.Errorf("sec-ch-ua-model %s doesn't match ua %s", model, ua)
+		}
+	})
+	t.Run("desktop chrome has no model", func(t *testing.T) {
+		h := NewHeaders(
+			ChromeOnly(true),
+		)
+		if v, ok := h.Headers()["sec-ch-ua-model"]; ok {
+			t.Errorf("got %v but wanted no 'sec-ch-ua-model' header", v)
+		}
+	})
+	t.Run("ios picks an iphone or ipad ua", func(t *testing.T) {
+		for i := 0; i < 20; i++ {
+			h := NewHeaders(
+				WithOS("i"),
+			)
+			ua := h.Headers()["User-Agent"][0]
+			if !strings.Contains(ua, "iPhone") && !strings.Contains(ua, "iPad") {
+				t.Fatalf("wanted an ios ua, got %s", ua)
+			}
+		}
+	})
+}
+
+func TestBrandList(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		brand    string
+		version  string
+		chromium string
+		full     bool
+		want     string
+	}{
+		{"chrome 100", "Google Chrome", "100.0.4896.127", "100.0.4896.127", false, `" Not A;Brand";v="99", "Chromium";v="100", "Google Chrome";v="100"`},
+		{"chrome 100 full", "Google Chrome", "100.0.4896.127", "100.0.4896.127", true, `" Not A;Brand";v="99.0.0.0", "Chromium";v="100.0.4896.127", "Google Chrome";v="100.0.4896.127"`},
+		{"chrome 103", "Google Chrome", "103.0.5060.134", "103.0.5060.134", false, `".Not/A)Brand";v="99", "Google Chrome";v="103", "Chromium";v="103"`},
+		{"chrome 104", "Google Chrome", "104.0.5112.102", "104.0.5112.102", false, `"Chromium";v="104", " Not A;Brand";v="99", "Google Chrome";v="104"`},
+		{"chrome 105", "Google Chrome", "105.0.5195.127", "105.0.5195.127", false, `"Google Chrome";v="105", "Not)A;Brand";v="8", "Chromium";v="105"`},
+		{"chrome 110", "Google Chrome", "110.0.5481.177", "110.0.5481.177", false, `"Chromium";v="110", "Not A(Brand";v="24", "Google Chrome";v="110"`},
+		{"chrome 112", "Google Chrome", "112.0.5615.137", "112.0.5615.137", false, `"Chromium";v="112", "Google Chrome";v="112", "Not:A-Brand";v="99"`},
+		{"chrome 108", "Google Chrome", "108.0.5359.125", "108.0.5359.125", false, `"Not?A_Brand";v="8", "Chromium";v="108", "Google Chrome";v="108"`},
+		{"edge 110 full", "Microsoft Edge", "110.0.1587.57", "110.0.5481.178", true, `"Chromium";v="110.0.5481.178", "Not A(Brand";v="24.0.0.0", "Microsoft Edge";v="110.0.1587.57"`},
+		{"opera 96", "Opera", "96.0.4693.80", "110.0.5481.178", false, `"Chromium";v="110", "Not A(Brand";v="24", "Opera";v="96"`},
+	}
+	for _, tt := range tests {
+		got := brandList(tt.brand, tt.version, tt.chromium, tt.full)
+		if got != tt.want {
+			t.Errorf("%s: got %s want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestChromiumRecordsMatchUserAgents(t *testing.T) {
+	t.Parallel()
+	for _, name := range []string{"chrome", "edge", "opera", "brave"} {
+		p, _ := LookupProfile(name)
+		for _, ua := range p.UserAgents {
+			chromium := ua.Chromium
+			if chromium == "" {
+				chromium = ua.Version
+			}
+			m := chromeMajor.FindStringSubmatch(ua.Value)
+			if m == nil || m[1] != strings.SplitN(chromium, ".", 2)[0] {
+				t.Errorf("%s: chromium %s doesn't match ua %s", name, chromium, ua.Value)
+			}
+		}
+	}
+}
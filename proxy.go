@@ -0,0 +1,270 @@
+package fuzzyHelpers
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ProxyStrategy is how WithProxies picks a proxy for each request.
+type ProxyStrategy int
+
+const (
+	// ProxyRoundRobin takes the proxies in turn.
+	ProxyRoundRobin ProxyStrategy = iota
+	// ProxyRandom picks one at random.
+	ProxyRandom
+	// ProxySticky sends everything for a host (host:port) through the
+	// same proxy, so the target sees one ip per host.
+	ProxySticky
+	// ProxyLeastFailures picks the one that has failed least often,
+	// taking them in turn among equals.
+	ProxyLeastFailures
+)
+
+const (
+	defaultProxyFailures = 3
+	defaultProxyCooldown = time.Minute
+)
+
+// ParseProxy parses a proxy url the way WithProxy and WithProxies do.
+// Proxies can be http, https, socks5 or socks5h, with a user and
+// password in the url if they need one (Basic auth for http). A bare
+// host:port is an http proxy.
+func ParseProxy(s string) (*url.URL, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, errors.New("empty proxy url")
+	}
+	if !strings.Contains(s, "://") {
+		s = "http://" + s
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("proxy %q: unsupported scheme %q", s, u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("proxy %q: no host", s)
+	}
+	return u, nil
+}
+
+// WithProxies sends requests through a pool of proxies, picked per
+// request by the WithProxyStrategy strategy (round robin unless set).
+// Proxies that fail too often are left out for a while, see
+// WithProxyHealth. Failing means the proxy couldn't be reached or
+// tunnelled through, or answered 407, or for plain http requests
+// through an http proxy, 502, 503 or 504. If a url doesn't parse (see ParseProxy), every
+// request fails with the parse error rather than going out without a
+// proxy.
+func WithProxies(proxies []string) optionClient {
+	return func(c *clientOptions) {
+		c.proxies = append(c.proxies, proxies...)
+	}
+}
+
+// WithProxyStrategy sets how WithProxies picks a proxy.
+func WithProxyStrategy(s ProxyStrategy) optionClient {
+	return func(c *clientOptions) {
+		c.proxyStrategy = s
+	}
+}
+
+// WithProxyHealth sets when a WithProxies proxy is taken out of the
+// pool: after failures requests in a row fail to get through it (3 by
+// default). It's tried again after cooldown (a minute by default), and
+// goes straight back out if that fails too. If every proxy is out, all
+// of them are used again rather than failing every request.
+func WithProxyHealth(failures int, cooldown time.Duration) optionClient {
+	return func(c *clientOptions) {
+		c.proxyFailures = failures
+		c.proxyCooldown = cooldown
+	}
+}
+
+// proxyPool implements WithProxies.
+type proxyPool struct {
+	strategy ProxyStrategy
+	failures int
+	cooldown time.Duration
+	now      func() time.Time
+	// err is why a proxy url didn't parse.
+	err error
+
+	mu      sync.Mutex
+	proxies []*poolProxy
+	next    int
+	sticky  map[string]*poolProxy
+}
+
+type poolProxy struct {
+	url *url.URL
+	// rt sends requests through the proxy, after any WithProxyChain
+	// hops.
+	rt http.RoundTripper
+	// failures counts failures in a row, total all of them.
+	failures  int
+	total     int
+	downUntil time.Time
+}
+
+// newProxyPool builds the pool for WithProxy, WithProxies and
+// WithProxyChain, with a transport per proxy made by base from a clone
+// of tr.
+func newProxyPool(c *clientOptions, tr *http.Transport, base func(*http.Transport) http.RoundTripper) *proxyPool {
+	p := &proxyPool{
+		strategy: c.proxyStrategy,
+		failures: c.proxyFailures,
+		cooldown: c.proxyCooldown,
+		now:      time.Now,
+		sticky:   map[string]*poolProxy{},
+	}
+	if p.failures <= 0 {
+		p.failures = defaultProxyFailures
+	}
+	if p.cooldown <= 0 {
+		p.cooldown = defaultProxyCooldown
+	}
+	chain, err := parseProxies(c.proxyChain)
+	if err != nil {
+		p.err = err
+		return p
+	}
+	proxies, err := parseProxies(c.proxies)
+	if err != nil {
+		p.err = err
+		return p
+	}
+	if len(proxies) == 0 {
+		// the chain's last hop is the only proxy.
+		proxies, chain = chain[len(chain)-1:], chain[:len(chain)-1]
+	}
+	for _, u := range proxies {
+		hops := append(append([]*url.URL(nil), chain...), u)
+		p.proxies = append(p.proxies, &poolProxy{
+			url: u,
+			rt:  routeTransport(tr, hops, c.proxyConnectHeader, base),
+		})
+	}
+	return p
+}
+
+// pick chooses the proxy for a request to host.
+func (p *proxyPool) pick(host string) *poolProxy {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := p.now()
+	var up []*poolProxy
+	for _, px := range p.proxies {
+		if !now.Before(px.downUntil) {
+			up = append(up, px)
+		}
+	}
+	if len(up) == 0 {
+		up = p.proxies
+	}
+	switch p.strategy {
+	case ProxyRandom:
+		return up[rand.Intn(len(up))]
+	case ProxySticky:
+		if px, ok := p.sticky[host]; ok && !now.Before(px.downUntil) {
+			return px
+		}
+		px := p.roundRobin(up)
+		p.sticky[host] = px
+		return px
+	case ProxyLeastFailures:
+		least := up[0].total
+		for _, px := range up {
+			if px.total < least {
+				least = px.total
+			}
+		}
+		var fewest []*poolProxy
+		for _, px := range up {
+			if px.total == least {
+				fewest = append(fewest, px)
+			}
+		}
+		return p.roundRobin(fewest)
+	}
+	return p.roundRobin(up)
+}
+
+// roundRobin takes the next of proxies in turn; p.mu must be held.
+func (p *proxyPool) roundRobin(proxies []*poolProxy) *poolProxy {
+	px := proxies[p.next%len(proxies)]
+	p.next++
+	return px
+}
+
+// report records whether a request got through px.
+func (p *proxyPool) report(px *poolProxy, ok bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if ok {
+		px.failures = 0
+		px.downUntil = time.Time{}
+		return
+	}
+	px.failures++
+	px.total++
+	if px.failures >= p.failures {
+		px.downUntil = p.now().Add(p.cooldown)
+	}
+}
+
+// proxyTransport picks a proxy for each request and keeps track of how
+// the proxies fare.
+type proxyTransport struct {
+	pool *proxyPool
+}
+
+func (t *proxyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.pool.err != nil {
+		return nil, t.pool.err
+	}
+	px := t.pool.pick(strings.ToLower(req.URL.Host))
+	resp, err := px.rt.RoundTrip(req)
+	if err != nil && req.Context().Err() != nil {
+		// the caller gave up, which isn't the proxy's fault.
+		return resp, err
+	}
+	t.pool.report(px, !proxyFailed(px, req, resp, err))
+	return resp, err
+}
+
+// proxyFailed reports whether a request through px failed because of
+// the proxy: it couldn't be reached or tunnelled through, or answered
+// for itself. Only proxies send 407, and an http proxy relaying plain
+// http answers 502, 503 or 504 when it can't get the response.
+func proxyFailed(px *poolProxy, req *http.Request, resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusProxyAuthRequired:
+		return true
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return req.URL.Scheme == "http" && (px.url.Scheme == "http" || px.url.Scheme == "https")
+	}
+	return false
+}
+
+func (t *proxyTransport) CloseIdleConnections() {
+	for _, px := range t.pool.proxies {
+		if c, ok := px.rt.(interface{ CloseIdleConnections() }); ok {
+			c.CloseIdleConnections()
+		}
+	}
+}
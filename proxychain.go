@@ -0,0 +1,175 @@
+package fuzzyHelpers
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+
+	"golang.org/x/net/proxy"
+)
+
+// WithProxyChain sends every connection through hops, in order, each
+// proxy connecting to the next: e.g. a local Burp, then a corporate
+// SOCKS proxy. The last hop connects to the target, or if WithProxy or
+// WithProxies are given, to the proxy picked for the request. Hops take
+// the same urls as WithProxy, including http proxies, which are
+// tunnelled through with CONNECT.
+func WithProxyChain(hops ...string) optionClient {
+	return func(c *clientOptions) {
+		c.proxyChain = append(c.proxyChain, hops...)
+	}
+}
+
+// WithProxyConnectHeader adds h to the CONNECT requests sent to http
+// proxies, e.g. a Proxy-Authorization other than the Basic one the
+// proxy url's user info gives.
+func WithProxyConnectHeader(h http.Header) optionClient {
+	return func(c *clientOptions) {
+		c.proxyConnectHeader = h.Clone()
+	}
+}
+
+// proxyChain dials through a series of proxies.
+type proxyChain struct {
+	hops     []*url.URL
+	header   http.Header
+	insecure bool
+	dial     func(ctx context.Context, network, addr string) (net.Conn, error)
+}
+
+// DialContext connects to addr through every hop of the chain.
+func (c *proxyChain) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	conn, err := c.dial(ctx, network, canonicalAddr(c.hops[0]))
+	if err != nil {
+		return nil, err
+	}
+	for i, hop := range c.hops {
+		next := addr
+		if i+1 < len(c.hops) {
+			next = canonicalAddr(c.hops[i+1])
+		}
+		if conn, err = c.through(ctx, conn, hop, next); err != nil {
+			return nil, fmt.Errorf("proxy %s: %w", hop.Host, err)
+		}
+	}
+	return conn, nil
+}
+
+// through has the proxy hop, at the other end of conn, connect it to
+// addr. conn is closed if that fails.
+func (c *proxyChain) through(ctx context.Context, conn net.Conn, hop *url.URL, addr string) (net.Conn, error) {
+	switch hop.Scheme {
+	case "http", "https":
+		conn, err := proxyTLS(ctx, conn, hop, c.insecure)
+		if err != nil {
+			return nil, err
+		}
+		if err := connectTunnel(ctx, conn, hop, addr, c.header); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return conn, nil
+	case "socks5", "socks5h":
+		tunnel, err := socksTunnel(ctx, conn, hop, addr)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return tunnel, nil
+	}
+	conn.Close()
+	return nil, fmt.Errorf("unsupported proxy scheme %q", hop.Scheme)
+}
+
+// socksTunnel has the socks5 proxy at the other end of conn connect it
+// to addr. socks5 resolves addr's host here and hands the proxy an ip,
+// ipv4 if there is one; socks5h leaves resolving it to the proxy.
+func socksTunnel(ctx context.Context, conn net.Conn, hop *url.URL, addr string) (net.Conn, error) {
+	if hop.Scheme == "socks5" {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		if net.ParseIP(host) == nil {
+			ips, err := net.DefaultResolver.LookupHost(ctx, host)
+			if err != nil {
+				return nil, err
+			}
+			// many proxies only speak ipv4 upstream.
+			ip := ips[0]
+			for _, candidate := range ips {
+				if parsed := net.ParseIP(candidate); parsed != nil && parsed.To4() != nil {
+					ip = candidate
+					break
+				}
+			}
+			addr = net.JoinHostPort(ip, port)
+		}
+	}
+	var auth *proxy.Auth
+	if hop.User != nil {
+		pass, _ := hop.User.Password()
+		auth = &proxy.Auth{User: hop.User.Username(), Password: pass}
+	}
+	d, err := proxy.SOCKS5("tcp", canonicalAddr(hop), auth, connDialer{conn})
+	if err != nil {
+		return nil, err
+	}
+	return d.(proxy.ContextDialer).DialContext(ctx, "tcp", addr)
+}
+
+// connDialer hands out a connection that's already open, for the socks
+// client to talk to the proxy over.
+type connDialer struct{ conn net.Conn }
+
+func (d connDialer) Dial(network, addr string) (net.Conn, error) {
+	return d.conn, nil
+}
+
+func (d connDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	return d.conn, nil
+}
+
+// routeTransport builds the transport for requests that go through
+// hops. An http(s) last hop is left to tr (or the ordered transport
+// base builds from it), which knows to send plain http requests to it
+// in absolute-form; every other hop is dialled through.
+func routeTransport(tr *http.Transport, hops []*url.URL, header http.Header, base func(*http.Transport) http.RoundTripper) http.RoundTripper {
+	rtr := tr.Clone()
+	chain := &proxyChain{header: header, dial: rtr.DialContext}
+	if rtr.TLSClientConfig != nil {
+		chain.insecure = rtr.TLSClientConfig.InsecureSkipVerify
+	}
+	if chain.dial == nil {
+		chain.dial = (&net.Dialer{Timeout: defaultDialTimeout, KeepAlive: defaultDialTimeout}).DialContext
+	}
+	last := hops[len(hops)-1]
+	switch last.Scheme {
+	case "http", "https":
+		rtr.Proxy = http.ProxyURL(last)
+		rtr.ProxyConnectHeader = header
+		chain.hops = hops[:len(hops)-1]
+	default:
+		rtr.Proxy = nil
+		chain.hops = hops
+	}
+	if len(chain.hops) > 0 {
+		rtr.DialContext = chain.DialContext
+	}
+	return base(rtr)
+}
+
+// parseProxies parses proxy urls with ParseProxy.
+func parseProxies(proxies []string) ([]*url.URL, error) {
+	var urls []*url.URL
+	for _, s := range proxies {
+		u, err := ParseProxy(s)
+		if err != nil {
+			return nil, err
+		}
+		urls = append(urls, u)
+	}
+	return urls, nil
+}
@@ -0,0 +1,360 @@
+package fuzzyHelpers
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// pipe copies between a and b until either side is done.
+func pipe(a, b net.Conn) {
+	done := make(chan struct{}, 2)
+	go func() { io.Copy(a, b); done <- struct{}{} }()
+	go func() { io.Copy(b, a); done <- struct{}{} }()
+	<-done
+	a.Close()
+	b.Close()
+}
+
+// socksServer is a minimal socks5 server, with username/password auth
+// if user is set. It records the addresses it's asked to connect to.
+type socksServer struct {
+	addr       string
+	user, pass string
+	mu         sync.Mutex
+	targets    []string
+}
+
+func newSocksServer(t *testing.T, user, pass string) *socksServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	s := &socksServer{addr: ln.Addr().String(), user: user, pass: pass}
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(c)
+		}
+	}()
+	return s
+}
+
+func (s *socksServer) seen() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.targets...)
+}
+
+func (s *socksServer) serve(c net.Conn) {
+	defer c.Close()
+	br := bufio.NewReader(c)
+	hdr := make([]byte, 2)
+	if _, err := io.ReadFull(br, hdr); err != nil || hdr[0] != 5 {
+		return
+	}
+	methods := make([]byte, hdr[1])
+	if _, err := io.ReadFull(br, methods); err != nil {
+		return
+	}
+	want := byte(0)
+	if s.user != "" {
+		want = 2
+	}
+	if !strings.ContainsRune(string(methods), rune(want)) {
+		c.Write([]byte{5, 0xff})
+		return
+	}
+	c.Write([]byte{5, want})
+	if want == 2 {
+		readString := func() string {
+			n, _ := br.ReadByte()
+			b := make([]byte, n)
+			io.ReadFull(br, b)
+			return string(b)
+		}
+		br.ReadByte() // subnegotiation version
+		user, pass := readString(), readString()
+		if user != s.user || pass != s.pass {
+			c.Write([]byte{1, 1})
+			return
+		}
+		c.Write([]byte{1, 0})
+	}
+	req := make([]byte, 4)
+	if _, err := io.ReadFull(br, req); err != nil || req[1] != 1 {
+		return
+	}
+	var host string
+	switch req[3] {
+	case 1:
+		ip := make([]byte, 4)
+		io.ReadFull(br, ip)
+		host = net.IP(ip).String()
+	case 3:
+		n, _ := br.ReadByte()
+		name := make([]byte, n)
+		io.ReadFull(br, name)
+		host = string(name)
+	case 4:
+		ip := make([]byte, 16)
+		io.ReadFull(br, ip)
+		host = net.IP(ip).String()
+	}
+	port := make([]byte, 2)
+	io.ReadFull(br, port)
+	target := net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port))))
+	s.mu.Lock()
+	s.targets = append(s.targets, target)
+	s.mu.Unlock()
+	up, err := net.Dial("tcp", target)
+	if err != nil {
+		c.Write([]byte{5, 5, 0, 1, 0, 0, 0, 0, 0, 0})
+		return
+	}
+	c.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+	pipe(&bufferedConn{c, br}, up)
+}
+
+// bufferedConn reads what a bufio.Reader already has before the rest.
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) { return c.r.Read(p) }
+
+// connectProxy is an http proxy that tunnels CONNECTs and forwards
+// absolute-form requests, requiring Basic auth if auth is set. It
+// records the header of each request it gets.
+type connectProxy struct {
+	url     string
+	auth    string
+	mu      sync.Mutex
+	headers []http.Header
+}
+
+func newConnectProxy(t *testing.T, auth string) *connectProxy {
+	t.Helper()
+	p := &connectProxy{auth: auth}
+	srv := httptest.NewServer(p)
+	t.Cleanup(srv.Close)
+	p.url = srv.URL
+	return p
+}
+
+func (p *connectProxy) seen() []http.Header {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]http.Header(nil), p.headers...)
+}
+
+func (p *connectProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	p.headers = append(p.headers, r.Header.Clone())
+	p.mu.Unlock()
+	if p.auth != "" && r.Header.Get("Proxy-Authorization") != p.auth {
+		w.WriteHeader(http.StatusProxyAuthRequired)
+		return
+	}
+	if r.Method != http.MethodConnect {
+		r.RequestURI = ""
+		r.Header.Del("Proxy-Authorization")
+		resp, err := http.DefaultTransport.RoundTrip(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		defer resp.Body.Close()
+		for k, vs := range resp.Header {
+			w.Header()[k] = vs
+		}
+		w.WriteHeader(resp.StatusCode)
+		io.Copy(w, resp.Body)
+		return
+	}
+	up, err := net.Dial("tcp", r.Host)
+	if err != nil {
+		w.WriteHeader(http.StatusBadGateway)
+		return
+	}
+	c, brw, err := w.(http.Hijacker).Hijack()
+	if err != nil {
+		up.Close()
+		return
+	}
+	io.WriteString(c, "HTTP/1.1 200 Connection established\r\n\r\n")
+	pipe(&bufferedConn{c, brw.Reader}, up)
+}
+
+// basic is the Proxy-Authorization for user:pass.
+func basic(user, pass string) string {
+	u, _ := ParseProxy("http://" + user + ":" + pass + "@x")
+	return proxyAuth(u)
+}
+
+func newTargets(t *testing.T) (plain, tls *httptest.Server) {
+	t.Helper()
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { io.WriteString(w, "hello") })
+	plain, tls = httptest.NewServer(h), httptest.NewTLSServer(h)
+	t.Cleanup(plain.Close)
+	t.Cleanup(tls.Close)
+	return plain, tls
+}
+
+// fetch gets url with c and checks it got through.
+func fetch(t *testing.T, c *http.Client, url string) error {
+	t.Helper()
+	resp, err := c.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "hello" {
+		t.Errorf("GET %s through the proxy: %s %q", url, resp.Status, body)
+	}
+	return nil
+}
+
+// clientVariants returns a net/http based client and one using the
+// ordered transport, both with opts.
+func clientVariants(opts ...optionClient) map[string]*http.Client {
+	return map[string]*http.Client{
+		"net/http": NewClient(opts...),
+		"ordered":  NewClient(append(opts, WithOrderedHeaders(true))...),
+	}
+}
+
+func TestSOCKS5Proxy(t *testing.T) {
+	t.Parallel()
+	plain, secure := newTargets(t)
+	_, port, _ := net.SplitHostPort(plain.Listener.Addr().String())
+	byName := "http://localhost:" + port + "/"
+
+	s := newSocksServer(t, "fuzz", "s3cret")
+	for name, c := range clientVariants(WithProxy("socks5://fuzz:s3cret@" + s.addr)) {
+		for _, u := range []string{plain.URL, secure.URL, byName} {
+			if err := fetch(t, c, u); err != nil {
+				t.Errorf("%s: GET %s: %v", name, u, err)
+			}
+		}
+	}
+	// socks5 resolves names itself.
+	for _, target := range s.seen() {
+		if strings.HasPrefix(target, "localhost") {
+			t.Errorf("socks5 sent the proxy a name, %s", target)
+		}
+	}
+
+	s = newSocksServer(t, "fuzz", "s3cret")
+	if err := fetch(t, NewClient(WithProxy("socks5h://fuzz:s3cret@"+s.addr)), byName); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.seen(); len(got) != 1 || got[0] != "localhost:"+port {
+		t.Errorf("socks5h asked the proxy for %v, want localhost:%s", got, port)
+	}
+
+	for name, c := range clientVariants(WithProxy("socks5://fuzz:wrong@" + s.addr)) {
+		if err := fetch(t, c, plain.URL); err == nil {
+			t.Errorf("%s: got through with the wrong password", name)
+		}
+	}
+}
+
+func TestHTTPProxyAuth(t *testing.T) {
+	t.Parallel()
+	plain, secure := newTargets(t)
+	p := newConnectProxy(t, basic("fuzz", "s3cret"))
+	u := strings.Replace(p.url, "http://", "http://fuzz:s3cret@", 1)
+	header := http.Header{"X-Engagement": {"42"}}
+	for name, c := range clientVariants(WithProxy(u), WithProxyConnectHeader(header)) {
+		for _, target := range []string{plain.URL, secure.URL} {
+			if err := fetch(t, c, target); err != nil {
+				t.Errorf("%s: GET %s: %v", name, target, err)
+			}
+		}
+	}
+	connects := 0
+	for _, h := range p.seen() {
+		if h.Get("X-Engagement") == "42" {
+			connects++
+		}
+	}
+	if connects != 2 {
+		t.Errorf("%d CONNECTs carried the custom header, want 2", connects)
+	}
+
+	for name, c := range clientVariants(WithProxy(p.url)) {
+		if err := fetch(t, c, secure.URL); err == nil {
+			t.Errorf("%s: got through without credentials", name)
+		}
+	}
+	// a Proxy-Authorization given as a CONNECT header wins.
+	c := NewClient(WithProxy(p.url), WithProxyConnectHeader(http.Header{"Proxy-Authorization": {basic("fuzz", "s3cret")}}))
+	if err := fetch(t, c, secure.URL); err != nil {
+		t.Errorf("with the CONNECT header's credentials: %v", err)
+	}
+}
+
+func TestProxyChain(t *testing.T) {
+	t.Parallel()
+	plain, secure := newTargets(t)
+	h := newConnectProxy(t, basic("burp", "pw"))
+	hURL := strings.Replace(h.url, "http://", "http://burp:pw@", 1)
+	s := newSocksServer(t, "corp", "pw")
+	sURL := "socks5://corp:pw@" + s.addr
+
+	tests := []struct {
+		name string
+		opts []optionClient
+	}{
+		{"http then socks", []optionClient{WithProxyChain(hURL, sURL)}},
+		{"socks then http", []optionClient{WithProxyChain(sURL, hURL)}},
+		{"chain then pool", []optionClient{WithProxyChain(hURL), WithProxies([]string{sURL})}},
+	}
+	for _, tt := range tests {
+		for name, c := range clientVariants(tt.opts...) {
+			for _, target := range []string{plain.URL, secure.URL} {
+				before := len(h.seen()) + len(s.seen())
+				if err := fetch(t, c, target); err != nil {
+					t.Errorf("%s, %s: GET %s: %v", tt.name, name, target, err)
+					continue
+				}
+				c.CloseIdleConnections()
+				if after := len(h.seen()) + len(s.seen()); after != before+2 {
+					t.Errorf("%s, %s: GET %s went through %d proxies, want 2", tt.name, name, target, after-before)
+				}
+			}
+		}
+	}
+}
+
+func TestProxyChainErrors(t *testing.T) {
+	t.Parallel()
+	plain, _ := newTargets(t)
+	s := newSocksServer(t, "corp", "pw")
+	for _, opts := range [][]optionClient{
+		{WithProxyChain("socks5://corp:pw@"+s.addr, "ftp://nope")},
+		{WithProxyChain("socks5://corp:wrong@"+s.addr, newConnectProxy(t, "").url)},
+	} {
+		if err := fetch(t, NewClient(opts...), plain.URL); err == nil {
+			t.Error("got through a broken chain")
+		}
+	}
+	if len(s.seen()) != 0 {
+		t.Errorf("socks server connected to %v for a broken chain", s.seen())
+	}
+}
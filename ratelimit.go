@@ -0,0 +1,183 @@
+package fuzzyHelpers
+
+import (
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimit caps how fast WithRateLimit lets requests out.
+type RateLimit struct {
+	// PerHost is the most requests per second sent to any one host
+	// (host:port), or 0 for no limit.
+	PerHost float64
+	// Global is the most requests per second sent overall, or 0 for no
+	// limit.
+	Global float64
+	// Burst is how many requests may go out back to back after a quiet
+	// spell before the limits kick in. Less than 1 means 1, i.e.
+	// requests are evenly spaced.
+	Burst int
+	// Delay adds a random wait of up to Delay before each request, so
+	// the requests don't arrive like clockwork.
+	Delay time.Duration
+}
+
+// WithRateLimit paces requests with a token bucket per host, and one
+// for all hosts if l.Global is set. Requests wait for their turn (or
+// until their context is done); retries and the WithAcceptCH resend
+// count as requests.
+func WithRateLimit(l RateLimit) optionClient {
+	return func(c *clientOptions) {
+		c.rateLimit = &l
+	}
+}
+
+// bucket is a token bucket. Tokens may go negative: each one owed is a
+// request waiting its turn.
+type bucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newBucket(rate float64, burst int) *bucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &bucket{rate: rate, burst: float64(burst), tokens: float64(burst)}
+}
+
+// refill adds the tokens earned since the bucket was last used.
+func (b *bucket) refill(now time.Time) {
+	if b.last.IsZero() {
+		b.last = now
+		return
+	}
+	if now.After(b.last) {
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+		b.last = now
+	}
+}
+
+// setRate changes the rate from now on.
+func (b *bucket) setRate(now time.Time, rate float64) {
+	b.refill(now)
+	b.rate = rate
+}
+
+// idle reports whether the bucket has refilled by now, so a new one
+// would be no different.
+func (b *bucket) idle(now time.Time) bool {
+	return b.tokens+now.Sub(b.last).Seconds()*b.rate >= b.burst
+}
+
+// pruneEvery is how often the per-host state of WithRateLimit and
+// WithThrottle is swept for hosts that have gone idle.
+const pruneEvery = time.Minute
+
+// take takes a token and returns how long until it's there.
+func (b *bucket) take(now time.Time) time.Duration {
+	b.refill(now)
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// rateLimitTransport implements WithRateLimit.
+type rateLimitTransport struct {
+	next  http.RoundTripper
+	limit RateLimit
+	now   func() time.Time
+
+	mu     sync.Mutex
+	global *bucket
+	// hosts holds the buckets of hosts sent to lately; a bucket is
+	// dropped once it has refilled, see prune.
+	hosts  map[string]*bucket
+	pruned time.Time
+}
+
+func newRateLimitTransport(next http.RoundTripper, l RateLimit) *rateLimitTransport {
+	t := &rateLimitTransport{next: next, limit: l, now: time.Now, hosts: map[string]*bucket{}}
+	if l.Global > 0 {
+		t.global = newBucket(l.Global, l.Burst)
+	}
+	return t
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	wait, cancel := t.reserve(strings.ToLower(canonicalAddr(req.URL)))
+	if t.limit.Delay > 0 {
+		wait += time.Duration(rand.Int63n(int64(t.limit.Delay) + 1))
+	}
+	if err := sleepCtx(req.Context(), wait); err != nil {
+		cancel()
+		return nil, err
+	}
+	return t.next.RoundTrip(req)
+}
+
+// reserve takes a token from host's bucket and the global one,
+// returning how long to wait for both and a func that hands them back
+// if the request gives up waiting.
+func (t *rateLimitTransport) reserve(host string) (time.Duration, func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.now()
+	t.prune(now)
+	var taken []*bucket
+	var wait time.Duration
+	if t.limit.PerHost > 0 {
+		b, ok := t.hosts[host]
+		if !ok {
+			b = newBucket(t.limit.PerHost, t.limit.Burst)
+			t.hosts[host] = b
+		}
+		taken = append(taken, b)
+	}
+	if t.global != nil {
+		taken = append(taken, t.global)
+	}
+	for _, b := range taken {
+		if d := b.take(now); d > wait {
+			wait = d
+		}
+	}
+	return wait, func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		for _, b := range taken {
+			b.tokens++
+		}
+	}
+}
+
+// prune drops the buckets that have refilled, at most every
+// pruneEvery, so hitting many hosts doesn't grow t.hosts for good; t.mu
+// must be held.
+func (t *rateLimitTransport) prune(now time.Time) {
+	if now.Sub(t.pruned) < pruneEvery {
+		return
+	}
+	t.pruned = now
+	for host, b := range t.hosts {
+		if b.idle(now) {
+			delete(t.hosts, host)
+		}
+	}
+}
+
+func (t *rateLimitTransport) CloseIdleConnections() {
+	if c, ok := t.next.(interface{ CloseIdleConnections() }); ok {
+		c.CloseIdleConnections()
+	}
+}
@@ -0,0 +1,189 @@
+package fuzzyHelpers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBucket(t *testing.T) {
+	t.Parallel()
+	start := time.Now()
+	b := newBucket(10, 3)
+	// the burst goes out at once, then one every 100ms.
+	for i, want := range []time.Duration{0, 0, 0, 100 * time.Millisecond, 200 * time.Millisecond} {
+		if got := b.take(start); got != want {
+			t.Errorf("take %d: wait %v, want %v", i, got, want)
+		}
+	}
+	// after a quiet second the burst is back, but no more than it.
+	later := start.Add(2 * time.Second)
+	for i := 0; i < 3; i++ {
+		if got := b.take(later); got != 0 {
+			t.Errorf("take %d after idling: wait %v, want 0", i, got)
+		}
+	}
+	if got := b.take(later); got != 100*time.Millisecond {
+		t.Errorf("take past the burst: wait %v, want 100ms", got)
+	}
+}
+
+// timeRequests sends n requests to each url concurrently and returns
+// how long they took.
+func timeRequests(t *testing.T, c *http.Client, n int, urls ...string) time.Duration {
+	t.Helper()
+	start := time.Now()
+	var wg sync.WaitGroup
+	for _, u := range urls {
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func(u string) {
+				defer wg.Done()
+				resp, err := c.Get(u)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				resp.Body.Close()
+			}(u)
+		}
+	}
+	wg.Wait()
+	return time.Since(start)
+}
+
+func TestRateLimit(t *testing.T) {
+	t.Parallel()
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	a := httptest.NewServer(handler)
+	t.Cleanup(a.Close)
+	b := httptest.NewServer(handler)
+	t.Cleanup(b.Close)
+
+	tests := []struct {
+		name     string
+		limit    RateLimit
+		urls     []string
+		min, max time.Duration
+	}{
+		// 5 requests at 20/s: the first goes right away, the rest 50ms apart.
+		{"per host", RateLimit{PerHost: 20}, []string{a.URL}, 190 * time.Millisecond, 2 * time.Second},
+		{"global", RateLimit{PerHost: 100, Global: 20}, []string{a.URL, b.URL}, 440 * time.Millisecond, 2 * time.Second},
+		{"burst", RateLimit{PerHost: 1, Burst: 5}, []string{a.URL}, 0, 500 * time.Millisecond},
+		{"delay", RateLimit{Delay: 100 * time.Millisecond}, []string{a.URL}, 0, 2 * time.Second},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			took := timeRequests(t, NewClient(WithRateLimit(tt.limit)), 5, tt.urls...)
+			if took < tt.min || took > tt.max {
+				t.Errorf("took %v, want between %v and %v", took, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestRateLimitHosts(t *testing.T) {
+	t.Parallel()
+	rt := newRateLimitTransport(nil, RateLimit{PerHost: 10})
+	for i := 0; i < 3; i++ {
+		rt.reserve("a.test")
+	}
+	// a.test's queue doesn't hold b.test up.
+	if wait, _ := rt.reserve("b.test"); wait != 0 {
+		t.Errorf("b.test waits %v behind a.test's requests, want 0", wait)
+	}
+	rt = newRateLimitTransport(nil, RateLimit{PerHost: 10, Global: 10})
+	for i := 0; i < 3; i++ {
+		rt.reserve("a.test")
+	}
+	if wait, _ := rt.reserve("b.test"); wait < 250*time.Millisecond {
+		t.Errorf("b.test waits %v with a global limit, want about 300ms", wait)
+	}
+}
+
+func TestRateLimitPrune(t *testing.T) {
+	t.Parallel()
+	clock := newFakeClock()
+	rt := newRateLimitTransport(nil, RateLimit{PerHost: 1, Burst: 2})
+	rt.now = clock.now
+	for i := 0; i < 1000; i++ {
+		rt.reserve(fmt.Sprintf("host%d.test:443", i))
+	}
+	for i := 0; i < 100; i++ {
+		rt.reserve("busy.test:443")
+	}
+	clock.add(pruneEvery)
+	rt.reserve("new.test:443")
+	// busy.test's queue outlasted the sweep; the refilled ones are gone.
+	if len(rt.hosts) != 2 || rt.hosts["busy.test:443"] == nil {
+		t.Errorf("kept %d buckets after the sweep, want busy.test's and new.test's", len(rt.hosts))
+	}
+}
+
+// okTransport answers every request with an empty 200.
+type okTransport struct{}
+
+func (okTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+}
+
+func TestRateLimitHostKey(t *testing.T) {
+	t.Parallel()
+	rt := newRateLimitTransport(okTransport{}, RateLimit{PerHost: 10})
+	send := func(u string) time.Duration {
+		req, _ := http.NewRequest(http.MethodGet, u, nil)
+		start := time.Now()
+		resp, err := rt.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return time.Since(start)
+	}
+	send("https://example.com/")
+	// the default port and a differently cased name are the same host.
+	if took := send("https://EXAMPLE.com:443/"); took < 80*time.Millisecond {
+		t.Errorf("example.com:443 went out after %v, want about 100ms", took)
+	}
+	if took := send("http://example.com/"); took > 50*time.Millisecond {
+		t.Errorf("example.com:80 waited %v behind example.com:443", took)
+	}
+}
+
+func TestRateLimitContext(t *testing.T) {
+	t.Parallel()
+	var hits int
+	var mu sync.Mutex
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits++
+		mu.Unlock()
+	}))
+	defer srv.Close()
+	c := NewClient(WithRateLimit(RateLimit{PerHost: 0.1}))
+	resp, err := c.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	// the next token is 10s off; the request gives up at its deadline.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	if _, err := c.Do(req); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v, want the context's error", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if hits != 1 {
+		t.Errorf("server saw %d requests, want 1", hits)
+	}
+}
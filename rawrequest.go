@@ -0,0 +1,277 @@
+package fuzzyHelpers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// RawRequest is a request read from a raw HTTP/1.1 request, the way
+// Burp and ZAP save them.
+type RawRequest struct {
+	Method string
+	// URL is the request's url. Raw requests don't say whether they
+	// were sent over tls, so unless the request line has an absolute
+	// url it's assumed they were (https).
+	URL   *url.URL
+	Proto string
+	// Header holds the headers in the order, and with the spelling,
+	// they were captured with.
+	Header []HeaderField
+	// Body is the request body, with any chunked encoding removed.
+	Body []byte
+}
+
+// ReadRawRequest parses a raw HTTP/1.1 request: a request line,
+// headers, a blank line and the body. Lines may end in \r\n or \n, and
+// a body longer than its Content-Length (e.g. a newline added by an
+// editor) is cut down to it.
+func ReadRawRequest(r io.Reader) (*RawRequest, error) {
+	br := bufio.NewReader(r)
+	readLine := func() (string, error) {
+		line, err := br.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			return "", err
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+	line, err := readLine()
+	// tolerate blank lines before the request line.
+	for err == nil && strings.TrimSpace(line) == "" {
+		line, err = readLine()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading request line: %w", err)
+	}
+	parts := strings.Fields(line)
+	if len(parts) < 2 || len(parts) > 3 {
+		return nil, fmt.Errorf("malformed request line %q", line)
+	}
+	raw := &RawRequest{Method: parts[0], Proto: "HTTP/1.1"}
+	if len(parts) == 3 {
+		raw.Proto = parts[2]
+	}
+	for {
+		line, err := readLine()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if line == "" {
+			break
+		}
+		// obsolete line folding continues the previous header.
+		if (line[0] == ' ' || line[0] == '\t') && len(raw.Header) > 0 {
+			raw.Header[len(raw.Header)-1].Value += " " + strings.TrimSpace(line)
+			continue
+		}
+		i := strings.Index(line, ":")
+		if i <= 0 {
+			return nil, fmt.Errorf("malformed header line %q", line)
+		}
+		raw.Header = append(raw.Header, HeaderField{
+			Name:  strings.TrimSpace(line[:i]),
+			Value: strings.TrimSpace(line[i+1:]),
+		})
+	}
+	if raw.URL, err = raw.target(parts[1]); err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(br)
+	if err != nil {
+		return nil, err
+	}
+	if strings.EqualFold(raw.get("Transfer-Encoding"), "chunked") {
+		if body, err = io.ReadAll(httputil.NewChunkedReader(bytes.NewReader(body))); err != nil {
+			return nil, fmt.Errorf("reading chunked body: %w", err)
+		}
+	} else if n, err := strconv.Atoi(raw.get("Content-Length")); err == nil && n >= 0 && n < len(body) {
+		body = body[:n]
+	}
+	if len(body) > 0 {
+		raw.Body = body
+	}
+	return raw, nil
+}
+
+// ReadRawRequestFile is ReadRawRequest reading from the file at path.
+func ReadRawRequestFile(path string) (*RawRequest, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadRawRequest(f)
+}
+
+// target resolves the request target against the Host header.
+func (r *RawRequest) target(target string) (*url.URL, error) {
+	if strings.HasPrefix(target, "http://") || strings.HasPrefix(target, "https://") {
+		return url.ParseRequestURI(target)
+	}
+	if !strings.HasPrefix(target, "/") {
+		return nil, fmt.Errorf("unsupported request target %q", target)
+	}
+	host := r.get("Host")
+	if host == "" {
+		return nil, errors.New("request has no Host header")
+	}
+	return url.ParseRequestURI("https://" + host + target)
+}
+
+// get returns the value of the first header named name.
+func (r *RawRequest) get(name string) string {
+	for _, f := range r.Header {
+		if strings.EqualFold(f.Name, name) {
+			return f.Value
+		}
+	}
+	return ""
+}
+
+// Request builds an http.Request that replays r, Host going into
+// req.Host. Header names keep their captured spelling, apart from the
+// ones net/http only recognizes canonicalized (User-Agent), and
+// Content-Length and Transfer-Encoding are left to net/http.
+func (r *RawRequest) Request(ctx context.Context) (*http.Request, error) {
+	if r.URL == nil {
+		return nil, errors.New("request has no url")
+	}
+	var body io.Reader
+	if r.Body != nil {
+		body = bytes.NewReader(r.Body)
+	}
+	req, err := http.NewRequestWithContext(ctx, r.Method, r.URL.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range r.Header {
+		if f.Name == "" || skipRawHeader(f.Name) {
+			continue
+		}
+		name := f.Name
+		if strings.EqualFold(name, "Host") {
+			req.Host = f.Value
+			continue
+		}
+		if strings.EqualFold(name, "User-Agent") {
+			name = "User-Agent"
+		}
+		req.Header[name] = append(req.Header[name], f.Value)
+	}
+	return req, nil
+}
+
+// skipRawHeader reports whether a captured header describes the
+// captured body's framing, which net/http works out for itself.
+func skipRawHeader(name string) bool {
+	return strings.EqualFold(name, "Content-Length") || strings.EqualFold(name, "Transfer-Encoding")
+}
+
+// WithRawRequest merges the headers of a captured request with the
+// generated ones. With override the captured headers win where both
+// have one, so the capture is reproduced as closely as possible, and a
+// captured User-Agent picks the profile, so the client hints and the
+// rest follow it; without it the generated ones do, and captured headers only fill in
+// what the profile doesn't send (cookies, authorization and the like).
+// Names are matched regardless of case, SuppressHeaders applies to the
+// captured headers as well, and the captured url and method stand in
+// for WithURL and WithMethod if those aren't given.
+func WithRawRequest(raw *RawRequest, override bool) optionHeaders {
+	return func(h *headers) {
+		if raw == nil {
+			return
+		}
+		h.raw, h.rawOverride = raw, override
+		if h.nav.to == nil && raw.URL != nil {
+			h.nav.to = raw.URL
+		}
+		if h.nav.method == "" {
+			h.nav.method = strings.ToUpper(raw.Method)
+		}
+	}
+}
+
+// mergeRaw merges the WithRawRequest headers into hm.
+func (h *headers) mergeRaw(hm headerMap) {
+	if h.raw == nil {
+		return
+	}
+	// the spelling each captured header went in with, or "" where the
+	// generated one was kept.
+	spelling := map[string]string{}
+	for _, f := range h.raw.Header {
+		if f.Name == "" || skipRawHeader(f.Name) || h.suppressedFold(f.Name) {
+			continue
+		}
+		lk := strings.ToLower(f.Name)
+		if name, ok := spelling[lk]; ok {
+			// repeated captured headers keep every value.
+			if name != "" {
+				hm[name] = append(hm[name], f.Value)
+			}
+			continue
+		}
+		var generated []string
+		for k := range hm {
+			if strings.ToLower(k) == lk {
+				generated = append(generated, k)
+			}
+		}
+		if len(generated) > 0 && !h.rawOverride {
+			spelling[lk] = ""
+			continue
+		}
+		for _, k := range generated {
+			delete(hm, k)
+		}
+		hm[f.Name] = []string{f.Value}
+		spelling[lk] = f.Name
+	}
+	if _, _, ok := h.rawUserAgent(); !ok && spelling["user-agent"] != "" {
+		// no profile knows the captured ua's browser, so the generated
+		// client hints would describe another one.
+		for k := range hm {
+			lk := strings.ToLower(k)
+			if strings.HasPrefix(lk, "sec-ch-") && spelling[lk] == "" {
+				delete(hm, k)
+			}
+		}
+	}
+}
+
+// rawUserAgent returns the profile and ua of the captured User-Agent,
+// if it wins over the generated one and a profile knows its browser.
+func (h *headers) rawUserAgent() (*Profile, UserAgent, bool) {
+	if h.raw == nil || !h.rawOverride || h.suppressedFold("User-Agent") {
+		return nil, UserAgent{}, false
+	}
+	name, ua, ok := parseUserAgent(h.raw.get("User-Agent"))
+	if !ok {
+		return nil, UserAgent{}, false
+	}
+	p, ok := h.lookup(name)
+	return p, ua, ok
+}
+
+// suppressedFold is suppressed ignoring case, for captured headers,
+// whose spelling depends on where they were captured.
+func (h *headers) suppressedFold(k string) bool {
+	for _, v := range h.suppressHeaders {
+		if strings.EqualFold(k, v) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,195 @@
+package fuzzyHelpers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const burpRequest = "POST /login?next=%2F HTTP/1.1\r\n" +
+	"Host: example.com\r\n" +
+	"Cookie: session=abc\r\n" +
+	"sec-ch-ua: \"Chromium\";v=\"120\"\r\n" +
+	"User-Agent: captured\r\n" +
+	"Content-Type: application/x-www-form-urlencoded\r\n" +
+	"Content-Length: 17\r\n" +
+	"X-Folded: one\r\n" +
+	"\ttwo\r\n" +
+	"\r\n" +
+	"user=bob&pass=pw1\n"
+
+func TestReadRawRequest(t *testing.T) {
+	t.Parallel()
+	raw, err := ReadRawRequest(strings.NewReader(burpRequest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if raw.Method != "POST" || raw.Proto != "HTTP/1.1" {
+		t.Errorf("got %s %s", raw.Method, raw.Proto)
+	}
+	if got := raw.URL.String(); got != "https://example.com/login?next=%2F" {
+		t.Errorf("got url %s", got)
+	}
+	want := []HeaderField{
+		{"Host", "example.com"},
+		{"Cookie", "session=abc"},
+		{"sec-ch-ua", `"Chromium";v="120"`},
+		{"User-Agent", "captured"},
+		{"Content-Type", "application/x-www-form-urlencoded"},
+		{"Content-Length", "17"},
+		{"X-Folded", "one two"},
+	}
+	if !reflect.DeepEqual(raw.Header, want) {
+		t.Errorf("got headers\n%v\nwant\n%v", raw.Header, want)
+	}
+	if got := string(raw.Body); got != "user=bob&pass=pw1" {
+		t.Errorf("got body %q", got)
+	}
+}
+
+func TestReadRawRequestForms(t *testing.T) {
+	t.Parallel()
+	raw, err := ReadRawRequest(strings.NewReader("\nGET http://example.com:8080/a HTTP/1.1\nHost: example.com:8080\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if raw.URL.String() != "http://example.com:8080/a" || raw.Body != nil {
+		t.Errorf("got %s %q", raw.URL, raw.Body)
+	}
+	raw, err = ReadRawRequest(strings.NewReader("POST /up HTTP/1.1\r\nHost: example.com\r\nTransfer-Encoding: chunked\r\n\r\n5\r\nhello\r\n6\r\n world\r\n0\r\n\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(raw.Body) != "hello world" {
+		t.Errorf("got chunked body %q", raw.Body)
+	}
+	for _, bad := range []string{
+		"",
+		"GET\r\n\r\n",
+		"GET / HTTP/1.1\r\nAccept: */*\r\n\r\n",
+		"GET / HTTP/1.1\r\nHost: example.com\r\nnot a header\r\n\r\n",
+		"OPTIONS * HTTP/1.1\r\nHost: example.com\r\n\r\n",
+	} {
+		if _, err := ReadRawRequest(strings.NewReader(bad)); err == nil {
+			t.Errorf("%q: wanted an error", bad)
+		}
+	}
+}
+
+func TestRawRequestReplay(t *testing.T) {
+	t.Parallel()
+	var got *http.Request
+	var body string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		got, body = r, string(b)
+	}))
+	defer ts.Close()
+
+	path := filepath.Join(t.TempDir(), "login.req")
+	if err := os.WriteFile(path, []byte(burpRequest), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	raw, err := ReadRawRequestFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw.URL.Scheme, raw.URL.Host = "http", strings.TrimPrefix(ts.URL, "http://")
+	req, err := raw.Request(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := req.Header["sec-ch-ua"]; !ok {
+		t.Error("sec-ch-ua lost its spelling")
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got.Method != "POST" || got.Host != "example.com" || got.URL.RequestURI() != "/login?next=%2F" {
+		t.Errorf("got %s %s %s", got.Method, got.Host, got.URL.RequestURI())
+	}
+	if got.UserAgent() != "captured" || got.Header.Get("Cookie") != "session=abc" {
+		t.Errorf("got headers %v", got.Header)
+	}
+	if body != "user=bob&pass=pw1" || got.ContentLength != 17 {
+		t.Errorf("got body %q (%d)", body, got.ContentLength)
+	}
+}
+
+func TestWithRawRequest(t *testing.T) {
+	t.Parallel()
+	raw, err := ReadRawRequest(strings.NewReader(burpRequest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Run("generated headers win", func(t *testing.T) {
+		headers := NewHeaders(WithProfile("chrome"), WithRawRequest(raw, false), SuppressHeaders("cookie")).Headers()
+		if got := headers.Get("User-Agent"); got == "captured" {
+			t.Error("captured ua replaced the generated one")
+		}
+		if got := headerValue(headers, "sec-ch-ua"); !strings.Contains(got, "Google Chrome") {
+			t.Errorf("got sec-ch-ua %s", got)
+		}
+		if got := headers.Get("Content-Type"); got != "application/x-www-form-urlencoded" {
+			t.Errorf("got Content-Type %q", got)
+		}
+		if _, ok := headers["Cookie"]; ok {
+			t.Error("suppressed Cookie was sent")
+		}
+		if _, ok := headers["Content-Length"]; ok {
+			t.Error("captured Content-Length was sent")
+		}
+		if got := headers.Get("Host"); got != "example.com" {
+			t.Errorf("got Host %q", got)
+		}
+	})
+	t.Run("captured headers win", func(t *testing.T) {
+		headers := NewHeaders(WithProfile("chrome"), WithRawRequest(raw, true)).Headers()
+		if got := headers.Get("User-Agent"); got != "captured" {
+			t.Errorf("got ua %q", got)
+		}
+		if got := headers["sec-ch-ua"]; len(got) != 1 || got[0] != `"Chromium";v="120"` {
+			t.Errorf("got sec-ch-ua %v", got)
+		}
+		if got := headers.Get("Cookie"); got != "session=abc" {
+			t.Errorf("got Cookie %q", got)
+		}
+		if got := headers.Get("Sec-Fetch-Mode"); got != "navigate" {
+			t.Errorf("generated headers missing, got Sec-Fetch-Mode %q", got)
+		}
+		// no profile knows "captured", so chrome's hints can't stay.
+		if got := headerValue(headers, "sec-ch-ua-platform"); got != "" {
+			t.Errorf("generated sec-ch-ua-platform %s kept beside the captured ua", got)
+		}
+	})
+	t.Run("captured ua picks the profile", func(t *testing.T) {
+		uas := map[string]string{
+			"firefox": "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:121.0) Gecko/20100101 Firefox/121.0",
+			"chrome":  "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/119.0.0.0 Safari/537.36",
+		}
+		for _, other := range []string{"chrome", "firefox", "safari"} {
+			for browser, ua := range uas {
+				raw, err := ReadRawRequest(strings.NewReader("GET / HTTP/1.1\r\nHost: example.com\r\nUser-Agent: " + ua + "\r\n\r\n"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				h := NewHeaders(WithProfile(other), WithRawRequest(raw, true))
+				headers, c := h.Generate()
+				if c.Profile != browser || headers.Get("User-Agent") != ua {
+					t.Errorf("%s capture over %s: chose %s with ua %q", browser, other, c.Profile, headers.Get("User-Agent"))
+				}
+				if issues := Lint(headers); len(issues) > 0 {
+					t.Errorf("%s capture over %s: %v", browser, other, issues)
+				}
+			}
+		}
+	})
+}
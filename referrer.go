@@ -0,0 +1,152 @@
+package fuzzyHelpers
+
+import (
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// defaultReferrerPolicy is what every current browser uses when a page
+// doesn't set one.
+const defaultReferrerPolicy = "strict-origin-when-cross-origin"
+
+// navigation is where a request is going and the page it comes from,
+// which decide its Referer, Origin and Sec-Fetch-Site.
+type navigation struct {
+	from, to *url.URL
+	method   string
+}
+
+// apply returns the value a header derived from the navigation should
+// have in a request of the given Sec-Fetch-Mode, and whether it has one
+// at all. ok is false for headers the navigation doesn't decide, which
+// keep their template value.
+func (n navigation) apply(name, mode, policy string) (v string, ok bool) {
+	if n.from == nil {
+		return "", false
+	}
+	to := n.to
+	if to == nil {
+		to = n.from
+	}
+	switch name {
+	case "Referer":
+		return referrer(policy, n.from, to), true
+	case "Origin":
+		unsafe := n.method != "" && n.method != "GET" && n.method != "HEAD"
+		if !unsafe && (mode != "cors" || sameOrigin(n.from, to)) {
+			return "", true
+		}
+		// browsers send "null" where the referrer policy hides the
+		// page, except on cors requests, which always need it.
+		if mode != "cors" && referrer(policy, n.from, to) == "" {
+			return "null", true
+		}
+		return origin(n.from), true
+	case "Sec-Fetch-Site":
+		return fetchSite(n.from, to), true
+	}
+	return "", false
+}
+
+// referrer returns the Referer a browser sends from a page at from to
+// to, under the given referrer policy.
+func referrer(policy string, from, to *url.URL) string {
+	if from.Scheme != "http" && from.Scheme != "https" {
+		return ""
+	}
+	u := *from
+	u.Host = originHost(from)
+	u.User = nil
+	u.Fragment = ""
+	u.RawFragment = ""
+	full := u.String()
+	originOnly := origin(from) + "/"
+	downgrade := from.Scheme == "https" && to.Scheme != "https"
+	same := sameOrigin(from, to)
+	switch strings.ToLower(policy) {
+	case "no-referrer":
+		return ""
+	case "no-referrer-when-downgrade":
+		if downgrade {
+			return ""
+		}
+		return full
+	case "origin":
+		return originOnly
+	case "origin-when-cross-origin":
+		if same {
+			return full
+		}
+		return originOnly
+	case "same-origin":
+		if same {
+			return full
+		}
+		return ""
+	case "strict-origin":
+		if downgrade {
+			return ""
+		}
+		return originOnly
+	case "unsafe-url":
+		return full
+	default:
+		if same {
+			return full
+		}
+		if downgrade {
+			return ""
+		}
+		return originOnly
+	}
+}
+
+// origin serializes u's origin, e.g. "https://example.com:8443". Like
+// browsers, it leaves the scheme's default port out.
+func origin(u *url.URL) string {
+	return strings.ToLower(u.Scheme) + "://" + originHost(u)
+}
+
+// originHost is u's host as it appears in its origin: lower case, with
+// the port only if it isn't the scheme's default.
+func originHost(u *url.URL) string {
+	host := strings.ToLower(u.Hostname())
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	port := u.Port()
+	scheme := strings.ToLower(u.Scheme)
+	if port == "" || (scheme == "https" && port == "443") || (scheme == "http" && port == "80") {
+		return host
+	}
+	return host + ":" + port
+}
+
+func sameOrigin(a, b *url.URL) bool {
+	return strings.EqualFold(a.Scheme, b.Scheme) && originHost(a) == originHost(b)
+}
+
+// fetchSite returns the Sec-Fetch-Site of a request from a page at from
+// to to. Sites are compared the way browsers do, by scheme and
+// registrable domain, so a.example.com and b.example.com are same-site.
+func fetchSite(from, to *url.URL) string {
+	switch {
+	case sameOrigin(from, to):
+		return "same-origin"
+	case from.Scheme == to.Scheme && site(from) == site(to):
+		return "same-site"
+	}
+	return "cross-site"
+}
+
+// site returns u's registrable domain, or its host for ones without
+// one (ip addresses, localhost).
+func site(u *url.URL) string {
+	host := strings.ToLower(u.Hostname())
+	if d, err := publicsuffix.EffectiveTLDPlusOne(host); err == nil {
+		return d
+	}
+	return host
+}
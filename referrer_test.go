@@ -0,0 +1,198 @@
+package fuzzyHelpers
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func mustURL(t *testing.T, s string) *url.URL {
+	t.Helper()
+	u, err := url.Parse(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+func TestReferrer(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		policy, from, to, want string
+	}{
+		{"", "https://a.com/x?y=1#frag", "https://a.com/z", "https://a.com/x?y=1"},
+		{"", "https://user:pw@a.com/x", "https://a.com/z", "https://a.com/x"},
+		{"", "https://a.com/x", "https://b.com/z", "https://a.com/"},
+		{"", "https://a.com:8443/x", "https://a.com/z", "https://a.com:8443/"},
+		// default ports are left out, as browsers do.
+		{"", "https://A.com:443/x", "https://b.com/z", "https://a.com/"},
+		{"", "https://a.com:443/x", "https://a.com/z", "https://a.com/x"},
+		{"", "http://a.com:80/x", "http://a.com/z", "http://a.com/x"},
+		{"", "http://a.com:443/x", "http://b.com/z", "http://a.com:443/"},
+		{"", "https://a.com/x", "http://a.com/z", ""},
+		{"", "http://a.com/x", "https://b.com/z", "http://a.com/"},
+		{"no-referrer", "https://a.com/x", "https://a.com/z", ""},
+		{"no-referrer-when-downgrade", "https://a.com/x", "https://b.com/z", "https://a.com/x"},
+		{"no-referrer-when-downgrade", "https://a.com/x", "http://b.com/z", ""},
+		{"origin", "https://a.com/x", "https://a.com/z", "https://a.com/"},
+		{"origin-when-cross-origin", "https://a.com/x", "http://b.com/z", "https://a.com/"},
+		{"same-origin", "https://a.com/x", "https://b.com/z", ""},
+		{"strict-origin", "https://a.com/x", "https://a.com/z", "https://a.com/"},
+		{"unsafe-url", "https://a.com/x", "http://b.com/z", "https://a.com/x"},
+	}
+	for _, tt := range tests {
+		got := referrer(tt.policy, mustURL(t, tt.from), mustURL(t, tt.to))
+		if got != tt.want {
+			t.Errorf("%q %s -> %s: got %q want %q", tt.policy, tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestFetchSite(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		from, to, want string
+	}{
+		{"https://a.example.com/x", "https://a.example.com:443/y", "same-origin"},
+		{"https://a.example.com/x", "https://b.example.com/y", "same-site"},
+		{"https://example.co.uk/x", "https://www.example.co.uk/y", "same-site"},
+		{"https://a.co.uk/x", "https://b.co.uk/y", "cross-site"},
+		{"http://example.com/x", "https://example.com/y", "cross-site"},
+		{"https://example.com/x", "https://example.org/y", "cross-site"},
+		{"http://127.0.0.1:8080/x", "http://127.0.0.1:9090/y", "same-site"},
+		{"http://Example.com:80/x", "http://example.com/y", "same-origin"},
+	}
+	for _, tt := range tests {
+		if got := fetchSite(mustURL(t, tt.from), mustURL(t, tt.to)); got != tt.want {
+			t.Errorf("%s -> %s: got %s want %s", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestWithReferrer(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name                 string
+		opts                 []optionHeaders
+		referer, origin, sfs string
+	}{
+		{
+			name:    "same origin link",
+			opts:    []optionHeaders{WithReferrer("https://example.com/a?q=1"), WithURL("https://example.com/b")},
+			referer: "https://example.com/a?q=1", sfs: "same-origin",
+		},
+		{
+			name:    "cross site link",
+			opts:    []optionHeaders{WithReferrer("https://search.test/results"), WithURL("https://example.com/b")},
+			referer: "https://search.test/", sfs: "cross-site",
+		},
+		{
+			name:    "same origin form post",
+			opts:    []optionHeaders{WithReferrer("https://example.com/login"), WithURL("https://example.com/session"), WithMethod("post")},
+			referer: "https://example.com/login", origin: "https://example.com", sfs: "same-origin",
+		},
+		{
+			name:    "downgrading form post",
+			opts:    []optionHeaders{WithReferrer("https://example.com/login"), WithURL("http://example.com/session"), WithMethod("POST")},
+			referer: "", origin: "null", sfs: "cross-site",
+		},
+		{
+			name:    "cross origin fetch",
+			opts:    []optionHeaders{WithReferrer("https://app.example.com/"), WithURL("https://api.example.com/v1"), WithRequestType(RequestFetch)},
+			referer: "https://app.example.com/", origin: "https://app.example.com", sfs: "same-site",
+		},
+		{
+			name:    "same origin fetch",
+			opts:    []optionHeaders{WithReferrer("https://example.com/app"), WithURL("https://example.com/api"), WithRequestType(RequestFetch)},
+			referer: "https://example.com/app", sfs: "same-origin",
+		},
+		{
+			name:    "form post from a default port",
+			opts:    []optionHeaders{WithReferrer("https://example.com:443/login"), WithURL("https://example.com/session"), WithMethod("POST")},
+			referer: "https://example.com/login", origin: "https://example.com", sfs: "same-origin",
+		},
+		{
+			name:    "no referrer",
+			opts:    []optionHeaders{WithURL("https://example.com/b")},
+			referer: "", sfs: "none",
+		},
+	}
+	for _, name := range []string{"chrome", "firefox", "safari"} {
+		for _, tt := range tests {
+			opts := append([]optionHeaders{WithProfile(name), WithOS("m")}, tt.opts...)
+			headers := NewHeaders(opts...).Headers()
+			if got := headers.Get("Referer"); got != tt.referer {
+				t.Errorf("%s, %s: got Referer %q want %q", name, tt.name, got, tt.referer)
+			}
+			if got := headers.Get("Origin"); got != tt.origin {
+				t.Errorf("%s, %s: got Origin %q want %q", name, tt.name, got, tt.origin)
+			}
+			if got := headers.Get("Sec-Fetch-Site"); got != tt.sfs {
+				t.Errorf("%s, %s: got Sec-Fetch-Site %q want %q", name, tt.name, got, tt.sfs)
+			}
+		}
+	}
+}
+
+func TestIdentityNavigate(t *testing.T) {
+	t.Parallel()
+	id := NewIdentity(WithProfile("chrome"))
+	steps := []struct {
+		method, url, kind string
+		referer, sfs      string
+	}{
+		{"GET", "https://example.com/", "", "", "none"},
+		{"GET", "https://example.com/style.css", RequestStyle, "https://example.com/", "same-origin"},
+		// loading the stylesheet didn't move the persona off the page.
+		{"GET", "https://example.com/about", RequestNavigate, "https://example.com/", "same-origin"},
+		{"GET", "https://cdn.example.com/logo.png", RequestImage, "https://example.com/", "same-site"},
+		{"GET", "https://other.test/", RequestNavigate, "https://example.com/", "cross-site"},
+		{"POST", "https://other.test/search", RequestNavigate, "https://other.test/", "same-origin"},
+	}
+	for i, s := range steps {
+		headers, err := id.Navigate(s.method, s.url, s.kind)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := headers.Get("Referer"); got != s.referer {
+			t.Errorf("step %d: got Referer %q want %q", i, got, s.referer)
+		}
+		if got := headers.Get("Sec-Fetch-Site"); got != s.sfs {
+			t.Errorf("step %d: got Sec-Fetch-Site %q want %q", i, got, s.sfs)
+		}
+	}
+	if _, err := id.Navigate("GET", "not a url", ""); err == nil {
+		t.Error("wanted an error for a bad url")
+	}
+	id.Rotate()
+	headers, _ := id.Navigate("GET", "https://example.com/", "")
+	if v := headers.Get("Referer"); v != "" {
+		t.Errorf("got Referer %q after rotating", v)
+	}
+}
+
+func TestIdentityApply(t *testing.T) {
+	t.Parallel()
+	id := NewIdentity(WithProfile("chrome"))
+	steps := []struct {
+		method, url          string
+		referer, origin, sfs string
+	}{
+		{"GET", "https://example.com/", "", "", "none"},
+		{"POST", "https://example.com/login", "https://example.com/", "https://example.com", "same-origin"},
+		{"GET", "https://other.test/", "https://example.com/", "", "cross-site"},
+	}
+	for i, s := range steps {
+		req, _ := http.NewRequest(s.method, s.url, nil)
+		id.Apply(req)
+		if got := req.Header.Get("Referer"); got != s.referer {
+			t.Errorf("step %d: got Referer %q want %q", i, got, s.referer)
+		}
+		if got := req.Header.Get("Origin"); got != s.origin {
+			t.Errorf("step %d: got Origin %q want %q", i, got, s.origin)
+		}
+		if got := req.Header.Get("Sec-Fetch-Site"); got != s.sfs {
+			t.Errorf("step %d: got Sec-Fetch-Site %q want %q", i, got, s.sfs)
+		}
+	}
+}
@@ -0,0 +1,295 @@
+package fuzzyHelpers
+
+import (
+	"net/http"
+	"sort"
+)
+
+// request types accepted by WithRequestType. RequestNavigate, a top
+// level page load, is what Template describes and the default.
+const (
+	RequestNavigate = "navigate"
+	RequestFetch    = "fetch"
+	RequestXHR      = "xhr"
+	RequestScript   = "script"
+	RequestStyle    = "style"
+	RequestImage    = "image"
+	RequestFont     = "font"
+	RequestIframe   = "iframe"
+)
+
+const (
+	acceptXHR         = "application/json, text/javascript, */*; q=0.01"
+	acceptStyle       = "text/css,*/*;q=0.1"
+	acceptChromiumImg = "image/avif,image/webp,image/apng,image/svg+xml,image/*,*/*;q=0.8"
+	acceptFirefoxImg  = "image/avif,image/webp,*/*"
+	acceptSafariImg   = "image/webp,image/avif,video/*;q=0.8,image/png,image/svg+xml,image/*;q=0.8,*/*;q=0.5"
+	acceptFirefoxFont = "application/font-woff2;q=1.0,application/font-woff;q=0.9,*/*;q=0.8"
+	requestedWith     = "XMLHttpRequest"
+)
+
+// subresource describes a request type the way the browser templates
+// below need it.
+type subresource struct {
+	accept, mode, dest string
+}
+
+var subresources = map[string]subresource{
+	RequestFetch:  {"*/*", "cors", "empty"},
+	RequestXHR:    {acceptXHR, "cors", "empty"},
+	RequestScript: {"*/*", "no-cors", "script"},
+	RequestStyle:  {acceptStyle, "no-cors", "style"},
+	RequestImage:  {"", "no-cors", "image"},
+	RequestFont:   {"*/*", "cors", "font"},
+}
+
+// highEntropySlots are where chromium puts the high entropy hints in
+// subresource requests: the first four before sec-ch-ua-mobile, the rest
+// after sec-ch-ua-platform.
+var highEntropySlots = []HeaderField{
+	{"sec-ch-ua-arch", ""},
+	{"sec-ch-ua-bitness", ""},
+	{"sec-ch-ua-full-version", ""},
+	{"sec-ch-ua-full-version-list", ""},
+	{"sec-ch-ua-platform-version", ""},
+	{"sec-ch-ua-wow64", ""},
+}
+
+// chromiumRequests returns the non-navigation templates of the chromium
+// based browsers. extra goes after Accept, as in chromiumTemplate.
+func chromiumRequests(extra ...HeaderField) map[string][]HeaderField {
+	priority := map[string]string{
+		RequestFetch:  "u=1, i",
+		RequestXHR:    "u=1, i",
+		RequestScript: "u=1",
+		RequestStyle:  "u=0",
+		RequestImage:  "i",
+		RequestFont:   "u=0",
+	}
+	tmpls := map[string][]HeaderField{}
+	for kind, sr := range subresources {
+		accept := sr.accept
+		if kind == RequestImage {
+			accept = acceptChromiumImg
+		}
+		tmpl := []HeaderField{
+			{"Connection", "keep-alive"},
+			{"sec-ch-ua", ""},
+		}
+		// headers set by the page come right after sec-ch-ua.
+		if kind == RequestXHR {
+			tmpl = append(tmpl,
+				HeaderField{"Accept", accept},
+				HeaderField{"X-Requested-With", requestedWith},
+			)
+		}
+		tmpl = append(tmpl, highEntropySlots[:4]...)
+		tmpl = append(tmpl,
+			HeaderField{"sec-ch-ua-mobile", "?0"},
+			HeaderField{"sec-ch-ua-model", ""},
+			HeaderField{"User-Agent", ""},
+			HeaderField{"sec-ch-ua-platform", ""},
+		)
+		tmpl = append(tmpl, highEntropySlots[4:]...)
+		if kind != RequestXHR {
+			tmpl = append(tmpl, HeaderField{"Accept", accept})
+		}
+		tmpl = append(tmpl, extra...)
+		tmpls[kind] = append(tmpl,
+			HeaderField{"Origin", ""},
+			HeaderField{"Sec-Fetch-Site", "same-origin"},
+			HeaderField{"Sec-Fetch-Mode", sr.mode},
+			HeaderField{"Sec-Fetch-Dest", sr.dest},
+			HeaderField{"Referer", ""},
+			HeaderField{"Accept-Encoding", acceptEncoding},
+			HeaderField{"Accept-Language", ""},
+			HeaderField{"Priority", priority[kind]},
+		)
+	}
+	iframe := []HeaderField{
+		{"Connection", "keep-alive"},
+		{"sec-ch-ua", ""},
+		{"sec-ch-ua-arch", ""},
+		{"sec-ch-ua-bitness", ""},
+		{"sec-ch-ua-full-version", ""},
+		{"sec-ch-ua-full-version-list", ""},
+		{"sec-ch-ua-mobile", "?0"},
+		{"sec-ch-ua-model", ""},
+		{"sec-ch-ua-platform", ""},
+		{"sec-ch-ua-platform-version", ""},
+		{"sec-ch-ua-wow64", ""},
+		{"Upgrade-Insecure-Requests", "1"},
+		{"Origin", ""},
+		{"User-Agent", ""},
+		{"Accept", acceptChromiumHTML},
+	}
+	iframe = append(iframe, extra...)
+	tmpls[RequestIframe] = append(iframe,
+		HeaderField{"Sec-Fetch-Site", "same-origin"},
+		HeaderField{"Sec-Fetch-Mode", "navigate"},
+		HeaderField{"Sec-Fetch-Dest", "iframe"},
+		HeaderField{"Referer", ""},
+		HeaderField{"Accept-Encoding", acceptEncoding},
+		HeaderField{"Accept-Language", ""},
+		HeaderField{"Priority", "u=0, i"},
+	)
+	return tmpls
+}
+
+// firefoxRequests returns firefox's non-navigation templates.
+func firefoxRequests() map[string][]HeaderField {
+	priority := map[string]string{
+		RequestFetch:  "u=4",
+		RequestXHR:    "u=4",
+		RequestScript: "u=2",
+		RequestStyle:  "u=2",
+		RequestImage:  "u=5, i",
+		RequestFont:   "u=3",
+	}
+	tmpls := map[string][]HeaderField{}
+	for kind, sr := range subresources {
+		accept := sr.accept
+		switch kind {
+		case RequestImage:
+			accept = acceptFirefoxImg
+		case RequestFont:
+			accept = acceptFirefoxFont
+		}
+		tmpl := []HeaderField{
+			{"User-Agent", ""},
+			{"Accept", accept},
+			{"Accept-Language", ""},
+			{"Accept-Encoding", acceptEncoding},
+		}
+		if kind == RequestXHR {
+			tmpl = append(tmpl, HeaderField{"X-Requested-With", requestedWith})
+		}
+		tmpls[kind] = append(tmpl,
+			HeaderField{"Origin", ""},
+			HeaderField{"DNT", ""},
+			HeaderField{"Connection", "keep-alive"},
+			HeaderField{"Referer", ""},
+			HeaderField{"Sec-Fetch-Dest", sr.dest},
+			HeaderField{"Sec-Fetch-Mode", sr.mode},
+			HeaderField{"Sec-Fetch-Site", "same-origin"},
+			HeaderField{"Sec-GPC", ""},
+			HeaderField{"Priority", priority[kind]},
+		)
+	}
+	tmpls[RequestIframe] = []HeaderField{
+		{"User-Agent", ""},
+		{"Accept", acceptHTML},
+		{"Accept-Language", ""},
+		{"Accept-Encoding", acceptEncoding},
+		{"DNT", ""},
+		{"Connection", "keep-alive"},
+		{"Referer", ""},
+		{"Upgrade-Insecure-Requests", "1"},
+		{"Sec-Fetch-Dest", "iframe"},
+		{"Sec-Fetch-Mode", "navigate"},
+		{"Sec-Fetch-Site", "same-origin"},
+		{"Sec-GPC", ""},
+		{"Priority", "u=4, i"},
+	}
+	return tmpls
+}
+
+// safariRequests returns safari's non-navigation templates. safari
+// doesn't send Priority.
+func safariRequests() map[string][]HeaderField {
+	tmpls := map[string][]HeaderField{}
+	for kind, sr := range subresources {
+		accept := sr.accept
+		if kind == RequestImage {
+			accept = acceptSafariImg
+		}
+		tmpl := []HeaderField{
+			{"Accept", accept},
+			{"Sec-Fetch-Site", "same-origin"},
+			{"Origin", ""},
+			{"Sec-Fetch-Mode", sr.mode},
+			{"User-Agent", ""},
+			{"Referer", ""},
+			{"Accept-Language", ""},
+		}
+		if kind == RequestXHR {
+			tmpl = append(tmpl, HeaderField{"X-Requested-With", requestedWith})
+		}
+		tmpls[kind] = append(tmpl,
+			HeaderField{"Sec-Fetch-Dest", sr.dest},
+			HeaderField{"Accept-Encoding", acceptEncoding},
+			HeaderField{"Connection", "keep-alive"},
+		)
+	}
+	tmpls[RequestIframe] = []HeaderField{
+		{"Accept", acceptSafariHTML},
+		{"Sec-Fetch-Site", "same-origin"},
+		{"Sec-Fetch-Mode", "navigate"},
+		{"User-Agent", ""},
+		{"Referer", ""},
+		{"Accept-Language", ""},
+		{"Sec-Fetch-Dest", "iframe"},
+		{"Accept-Encoding", acceptEncoding},
+		{"Connection", "keep-alive"},
+	}
+	return tmpls
+}
+
+// template returns p's template for a request type, falling back to
+// the navigation template.
+func (p *Profile) template(kind string) []HeaderField {
+	if tmpl, ok := p.RequestTemplates[kind]; ok {
+		return tmpl
+	}
+	return p.Template
+}
+
+// requestTemplate works out which of p's templates a set of headers
+// was generated from, going by X-Requested-With and Sec-Fetch-*.
+func (p *Profile) requestTemplate(h http.Header) []HeaderField {
+	if headerValue(h, "X-Requested-With") != "" {
+		if tmpl, ok := p.RequestTemplates[RequestXHR]; ok {
+			return tmpl
+		}
+	}
+	dest, mode := headerValue(h, "Sec-Fetch-Dest"), headerValue(h, "Sec-Fetch-Mode")
+	if dest == "" || dest == "document" {
+		return p.Template
+	}
+	kinds := make([]string, 0, len(p.RequestTemplates))
+	for kind := range p.RequestTemplates {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	for _, kind := range kinds {
+		tmpl := p.RequestTemplates[kind]
+		if fieldValue(tmpl, "Sec-Fetch-Dest") == dest && fieldValue(tmpl, "Sec-Fetch-Mode") == mode {
+			return tmpl
+		}
+	}
+	return p.Template
+}
+
+// sends reports whether p's browser, in the version ua belongs to,
+// sends the named header at all.
+func (p *Profile) sends(name string, ua UserAgent) bool {
+	since, ok := p.Since[name]
+	if !ok {
+		return true
+	}
+	version := ua.Chromium
+	if version == "" {
+		version = ua.Version
+	}
+	return major(version) >= since
+}
+
+// fieldValue returns the value of the named field in tmpl.
+func fieldValue(tmpl []HeaderField, name string) string {
+	for _, f := range tmpl {
+		if f.Name == name {
+			return f.Value
+		}
+	}
+	return ""
+}
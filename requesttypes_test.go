@@ -0,0 +1,144 @@
+package fuzzyHelpers
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestWithRequestType(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		kind, dest, mode string
+	}{
+		{RequestFetch, "empty", "cors"},
+		{RequestXHR, "empty", "cors"},
+		{RequestScript, "script", "no-cors"},
+		{RequestStyle, "style", "no-cors"},
+		{RequestImage, "image", "no-cors"},
+		{RequestFont, "font", "cors"},
+		{RequestIframe, "iframe", "navigate"},
+	}
+	for _, name := range []string{"chrome", "firefox", "edge", "opera", "brave", "safari"} {
+		for _, tt := range tests {
+			h := NewHeaders(
+				WithProfile(name),
+				WithOS("m"),
+				WithRequestType(tt.kind),
+			)
+			nav := NewHeaders(WithProfile(name), WithOS("m")).Headers()
+			headers := h.Headers()
+			if got := headers.Get("Sec-Fetch-Dest"); got != tt.dest {
+				t.Errorf("%s %s: got Sec-Fetch-Dest %q want %q", name, tt.kind, got, tt.dest)
+			}
+			if got := headers.Get("Sec-Fetch-Mode"); got != tt.mode {
+				t.Errorf("%s %s: got Sec-Fetch-Mode %q want %q", name, tt.kind, got, tt.mode)
+			}
+			if got := headers.Get("Sec-Fetch-Site"); got != "same-origin" {
+				t.Errorf("%s %s: got Sec-Fetch-Site %q", name, tt.kind, got)
+			}
+			if v, ok := headers["Sec-Fetch-User"]; ok {
+				t.Errorf("%s %s: got Sec-Fetch-User %v", name, tt.kind, v)
+			}
+			if _, ok := headers["X-Requested-With"]; ok != (tt.kind == RequestXHR) {
+				t.Errorf("%s %s: X-Requested-With sent: %v", name, tt.kind, ok)
+			}
+			if _, ok := headers["Upgrade-Insecure-Requests"]; ok != (tt.kind == RequestIframe && name != "safari") {
+				t.Errorf("%s %s: Upgrade-Insecure-Requests sent: %v", name, tt.kind, ok)
+			}
+			if strings.HasPrefix(headers.Get("Accept"), "text/html") != (tt.kind == RequestIframe) {
+				t.Errorf("%s %s: got Accept %q", name, tt.kind, headers.Get("Accept"))
+			}
+			for _, k := range []string{"Accept-Language", "Accept-Encoding", "DNT", "Sec-GPC"} {
+				if got, want := headerValue(headers, k), headerValue(nav, k); got != want {
+					t.Errorf("%s %s: got %s %q want %q", name, tt.kind, k, got, want)
+				}
+			}
+			// none of the built in uas are new enough to send it.
+			if v, ok := headers["Priority"]; ok {
+				t.Errorf("%s %s: got Priority %v", name, tt.kind, v)
+			}
+		}
+	}
+}
+
+func TestUnknownRequestTypeNavigates(t *testing.T) {
+	t.Parallel()
+	headers := NewHeaders(WithProfile("firefox"), WithRequestType("carrier-pigeon")).Headers()
+	if got := headers.Get("Sec-Fetch-Dest"); got != "document" {
+		t.Errorf("got Sec-Fetch-Dest %q want document", got)
+	}
+}
+
+func TestPrioritySince(t *testing.T) {
+	t.Parallel()
+	p, _ := LookupProfile("edge")
+	tests := []struct {
+		ua   UserAgent
+		want bool
+	}{
+		{UserAgent{Version: "124.0.2478.51"}, true},
+		{UserAgent{Version: "112.0.1722.48", Chromium: "112.0.5615.138"}, false},
+		// edge 123 on chromium 124 would send it
+		{UserAgent{Version: "123.0.0.0", Chromium: "124.0.0.0"}, true},
+	}
+	for _, tt := range tests {
+		if got := p.sends("Priority", tt.ua); got != tt.want {
+			t.Errorf("%+v: got %v want %v", tt.ua, got, tt.want)
+		}
+	}
+	if !p.sends("Accept", UserAgent{Version: "1.0"}) {
+		t.Error("headers without a version gate should always be sent")
+	}
+}
+
+func TestIdentityRequestHeaders(t *testing.T) {
+	t.Parallel()
+	id := NewIdentity(WithProfile("chrome"))
+	nav := id.Headers()
+	xhr := id.RequestHeaders(RequestXHR)
+	if xhr.Get("X-Requested-With") != "XMLHttpRequest" {
+		t.Errorf("got X-Requested-With %q", xhr.Get("X-Requested-With"))
+	}
+	for _, name := range []string{"User-Agent", "sec-ch-ua", "sec-ch-ua-platform", "Accept-Language"} {
+		if headerValue(xhr, name) != headerValue(nav, name) {
+			t.Errorf("%s changed between navigation and xhr: %q vs %q", name, headerValue(nav, name), headerValue(xhr, name))
+		}
+	}
+}
+
+func TestOrderedRequestType(t *testing.T) {
+	t.Parallel()
+	s := newRawServer(t)
+	c := NewClient(
+		WithOrderedHeaders(true),
+	)
+	for _, kind := range []string{RequestXHR, RequestImage} {
+		headers := NewHeaders(
+			WithProfile("chrome"),
+			WithRequestType(kind),
+		).Headers()
+		req, _ := http.NewRequest("GET", s.url(), nil)
+		req.Header = headers
+		resp, err := c.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		s.mu.Lock()
+		lines := s.reqs[len(s.reqs)-1]
+		s.mu.Unlock()
+		p, _ := LookupProfile("chrome")
+		want := []string{"Host"}
+		for _, f := range p.RequestTemplates[kind] {
+			if _, ok := headers[f.Name]; ok {
+				want = append(want, f.Name)
+			}
+		}
+		got := headerNames(lines[1:])
+		if strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Errorf("%s: got header order\n%v\nwant\n%v", kind, got, want)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package fuzzyHelpers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy says which failed requests WithRetry sends again, and
+// how long it waits in between. Fields left zero turn the feature off,
+// so start from DefaultRetryPolicy to change only some of them.
+type RetryPolicy struct {
+	// Attempts is how many times a request is sent at most, the first
+	// time included.
+	Attempts int
+	// Backoff returns how long to wait before retry n (1 for the
+	// first), e.g. ExponentialBackoff.
+	Backoff func(n int) time.Duration
+	// Jitter randomizes each wait by up to this fraction of it, so
+	// with 0.5 a 1s backoff waits between 0.5s and 1s. It keeps many
+	// clients (or goroutines) from retrying in lockstep.
+	Jitter float64
+	// Statuses are the response codes that are retried.
+	Statuses []int
+	// RetryError reports whether a request that failed with err is
+	// retried, e.g. RetryableError.
+	RetryError func(err error) bool
+	// NonIdempotent allows retrying methods that may not be safe to
+	// repeat (POST, PATCH, ...). Requests with an Idempotency-Key
+	// header are retried regardless.
+	NonIdempotent bool
+}
+
+// DefaultRetryPolicy sends a request up to three times, waiting
+// around 200ms and then 400ms, on connection errors and 502, 503 and
+// 504 responses.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		Attempts:   3,
+		Backoff:    ExponentialBackoff(200*time.Millisecond, 10*time.Second),
+		Jitter:     0.5,
+		Statuses:   []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout},
+		RetryError: RetryableError,
+	}
+}
+
+// ExponentialBackoff waits base before the first retry and doubles it
+// for each one after, up to max.
+func ExponentialBackoff(base, max time.Duration) func(n int) time.Duration {
+	return func(n int) time.Duration {
+		d := base
+		for i := 1; i < n && d < max; i++ {
+			d *= 2
+		}
+		if d > max {
+			d = max
+		}
+		return d
+	}
+}
+
+// RetryableError reports whether err looks transient: a reset, refused
+// or dropped connection, or a timeout. Cancelled requests, and ones
+// whose context ran out, aren't retried.
+func RetryableError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	for _, target := range []error{io.EOF, io.ErrUnexpectedEOF, syscall.ECONNRESET, syscall.ECONNREFUSED, syscall.ECONNABORTED, syscall.EPIPE} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsTimeout || dnsErr.IsTemporary
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// WithRetry sends requests that fail in the ways p describes again,
+// backing off in between. Bodies are rewound with GetBody, which
+// http.NewRequest sets for the usual body types; requests with a body
+// and no GetBody are only sent once. Note that the client's Timeout
+// covers all attempts together.
+func WithRetry(p RetryPolicy) optionClient {
+	return func(c *clientOptions) {
+		c.retry = &p
+	}
+}
+
+// retryTransport implements WithRetry.
+type retryTransport struct {
+	next   http.RoundTripper
+	policy RetryPolicy
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	retryable := t.repeatable(req)
+	for attempt := 1; ; attempt++ {
+		r := req
+		if attempt > 1 && req.Body != nil && req.Body != http.NoBody {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			r = req.Clone(req.Context())
+			r.Body = body
+		}
+		resp, err := t.next.RoundTrip(r)
+		if !retryable || attempt >= t.policy.Attempts || !t.failed(resp, err) {
+			return resp, err
+		}
+		if resp != nil {
+			io.CopyN(io.Discard, resp.Body, 4<<10)
+			resp.Body.Close()
+		}
+		if err := sleepCtx(req.Context(), t.wait(attempt)); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// repeatable reports whether req may be sent more than once.
+func (t *retryTransport) repeatable(req *http.Request) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	if _, ok := req.Header["Idempotency-Key"]; ok {
+		return true
+	}
+	if _, ok := req.Header["X-Idempotency-Key"]; ok {
+		return true
+	}
+	return t.policy.NonIdempotent
+}
+
+// failed reports whether the policy retries the outcome of a request.
+func (t *retryTransport) failed(resp *http.Response, err error) bool {
+	if err != nil {
+		return t.policy.RetryError != nil && t.policy.RetryError(err)
+	}
+	for _, code := range t.policy.Statuses {
+		if resp.StatusCode == code {
+			return true
+		}
+	}
+	return false
+}
+
+// wait returns how long to wait before retry n.
+func (t *retryTransport) wait(n int) time.Duration {
+	if t.policy.Backoff == nil {
+		return 0
+	}
+	d := t.policy.Backoff(n)
+	if j := t.policy.Jitter; j > 0 && d > 0 {
+		if j > 1 {
+			j = 1
+		}
+		d -= time.Duration(rand.Float64() * j * float64(d))
+	}
+	return d
+}
+
+// CloseIdleConnections lets http.Client.CloseIdleConnections reach the
+// transport underneath.
+func (t *retryTransport) CloseIdleConnections() {
+	if c, ok := t.next.(interface{ CloseIdleConnections() }); ok {
+		c.CloseIdleConnections()
+	}
+}
@@ -0,0 +1,213 @@
+package fuzzyHelpers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// fastRetry is DefaultRetryPolicy without the waiting.
+func fastRetry() RetryPolicy {
+	p := DefaultRetryPolicy()
+	p.Backoff = nil
+	return p
+}
+
+// flakyServer answers with fail for the first failures requests and
+// 200 after, echoing the body.
+func flakyServer(t *testing.T, failures int32, fail int) (*httptest.Server, *int32) {
+	t.Helper()
+	var n int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if atomic.AddInt32(&n, 1) <= failures {
+			w.WriteHeader(fail)
+			return
+		}
+		w.Write(body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &n
+}
+
+func TestRetryStatuses(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		failures int32
+		status   int
+		policy   func(*RetryPolicy)
+		want     int
+		attempts int32
+	}{
+		{"recovers", 2, http.StatusServiceUnavailable, nil, http.StatusOK, 3},
+		{"gives up", 5, http.StatusBadGateway, nil, http.StatusBadGateway, 3},
+		{"not retried", 1, http.StatusInternalServerError, nil, http.StatusInternalServerError, 1},
+		{"custom statuses", 1, http.StatusTooManyRequests, func(p *RetryPolicy) { p.Statuses = []int{http.StatusTooManyRequests} }, http.StatusOK, 2},
+		{"one attempt", 1, http.StatusServiceUnavailable, func(p *RetryPolicy) { p.Attempts = 1 }, http.StatusServiceUnavailable, 1},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			srv, n := flakyServer(t, tt.failures, tt.status)
+			p := fastRetry()
+			if tt.policy != nil {
+				tt.policy(&p)
+			}
+			resp, err := NewClient(WithRetry(p)).Get(srv.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.want {
+				t.Errorf("got status %d, want %d", resp.StatusCode, tt.want)
+			}
+			if got := atomic.LoadInt32(n); got != tt.attempts {
+				t.Errorf("got %d attempts, want %d", got, tt.attempts)
+			}
+		})
+	}
+}
+
+func TestRetryMethods(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name          string
+		method        string
+		key           bool
+		nonIdempotent bool
+		noGetBody     bool
+		attempts      int32
+	}{
+		{"put", http.MethodPut, false, false, false, 2},
+		{"post", http.MethodPost, false, false, false, 1},
+		{"post with idempotency key", http.MethodPost, true, false, false, 2},
+		{"post allowed", http.MethodPost, false, true, false, 2},
+		{"body can't be rewound", http.MethodPut, false, false, true, 1},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			srv, n := flakyServer(t, 1, http.StatusServiceUnavailable)
+			p := fastRetry()
+			p.NonIdempotent = tt.nonIdempotent
+			req, err := http.NewRequest(tt.method, srv.URL, strings.NewReader("payload"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.key {
+				req.Header.Set("Idempotency-Key", "abc")
+			}
+			if tt.noGetBody {
+				req.GetBody = nil
+			}
+			resp, err := NewClient(WithRetry(p)).Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if got := atomic.LoadInt32(n); got != tt.attempts {
+				t.Errorf("got %d attempts, want %d", got, tt.attempts)
+			}
+			if tt.attempts > 1 && string(body) != "payload" {
+				t.Errorf("retried body = %q, want payload", body)
+			}
+		})
+	}
+}
+
+func TestRetryConnectionErrors(t *testing.T) {
+	t.Parallel()
+	var n int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&n, 1) == 1 {
+			// drop the connection without answering.
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err == nil {
+				conn.Close()
+			}
+			return
+		}
+		io.WriteString(w, "ok")
+	}))
+	defer srv.Close()
+
+	resp, err := NewClient(WithRetry(fastRetry())).Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got := atomic.LoadInt32(&n); got != 2 {
+		t.Errorf("got %d attempts, want 2", got)
+	}
+
+	atomic.StoreInt32(&n, 0)
+	p := fastRetry()
+	p.RetryError = nil
+	if _, err := NewClient(WithRetry(p)).Get(srv.URL); err == nil {
+		t.Error("dropped connection retried with RetryError nil")
+	}
+}
+
+func TestRetryContext(t *testing.T) {
+	t.Parallel()
+	srv, n := flakyServer(t, 10, http.StatusServiceUnavailable)
+	p := DefaultRetryPolicy()
+	p.Backoff = func(int) time.Duration { return time.Hour }
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	_, err := NewClient(WithRetry(p)).Do(req)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v, want the context's error", err)
+	}
+	if got := atomic.LoadInt32(n); got != 1 {
+		t.Errorf("got %d attempts, want 1", got)
+	}
+}
+
+func TestRetryableError(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{io.EOF, true},
+		{&net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}, true},
+		{context.Canceled, false},
+		{errors.New("x509: certificate signed by unknown authority"), false},
+		{nil, false},
+	}
+	for _, tt := range tests {
+		if got := RetryableError(tt.err); got != tt.want {
+			t.Errorf("RetryableError(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	t.Parallel()
+	b := ExponentialBackoff(100*time.Millisecond, time.Second)
+	for n, want := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 4: 800 * time.Millisecond, 5: time.Second, 30: time.Second} {
+		if got := b(n); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", n, got, want)
+		}
+	}
+	rt := &retryTransport{policy: RetryPolicy{Backoff: b, Jitter: 0.5}}
+	for i := 0; i < 100; i++ {
+		if d := rt.wait(2); d < 100*time.Millisecond || d > 200*time.Millisecond {
+			t.Fatalf("jittered wait %v outside [100ms, 200ms]", d)
+		}
+	}
+}
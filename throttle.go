@@ -0,0 +1,332 @@
+package fuzzyHelpers
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ThrottleConfig tunes a Throttle. Fields left zero get the defaults
+// given for them.
+type ThrottleConfig struct {
+	// Start is the requests per second a host starts out with (10).
+	Start float64
+	// Min and Max bound the rate (0.5 and 100).
+	Min, Max float64
+	// Backoff multiplies the rate when a host pushes back (0.5).
+	Backoff float64
+	// Step is roughly how many requests per second the rate grows by
+	// each second while a host keeps up (1).
+	Step float64
+	// Slow is how many times its usual latency a response has to take
+	// to count as the host struggling (3).
+	Slow float64
+	// MaxPause caps how long a Retry-After holds a host up (5m).
+	MaxPause time.Duration
+	// OnChange, if set, is called with a host and its new rate whenever
+	// the rate changes, e.g. to log it.
+	OnChange func(host string, rate float64)
+}
+
+// Throttle adapts the request rate to each host (host:port) the way
+// TCP does to the network: it halves the rate when the host answers
+// 429 or 503, when requests fail to connect or are cut off, and when
+// responses take much longer than the host's usual, and raises it
+// slowly while requests go well. Retry-After, in seconds or as a date,
+// holds all requests to the host until then. It's safe for concurrent
+// use, so it can report the current rates while a client uses it.
+type Throttle struct {
+	cfg    ThrottleConfig
+	now    func() time.Time
+	mu     sync.Mutex
+	hosts  map[string]*throttleHost
+	pruned time.Time
+}
+
+type throttleHost struct {
+	bucket      *bucket
+	pausedUntil time.Time
+	// lastCut is when the rate was last cut. Cuts are at most a second
+	// apart, so one overloaded moment, which fails every request in
+	// flight, only counts once.
+	lastCut time.Time
+	latency time.Duration
+	samples int
+}
+
+// throttleLatencySamples is how many responses a host's usual latency
+// is averaged over before slow responses count.
+const throttleLatencySamples = 5
+
+func NewThrottle(cfg ThrottleConfig) *Throttle {
+	if cfg.Start <= 0 {
+		cfg.Start = 10
+	}
+	if cfg.Min <= 0 {
+		cfg.Min = 0.5
+	}
+	if cfg.Max <= 0 {
+		cfg.Max = 100
+	}
+	if cfg.Max < cfg.Min {
+		cfg.Max = cfg.Min
+	}
+	if cfg.Start < cfg.Min {
+		cfg.Start = cfg.Min
+	} else if cfg.Start > cfg.Max {
+		cfg.Start = cfg.Max
+	}
+	if cfg.Backoff <= 0 || cfg.Backoff >= 1 {
+		cfg.Backoff = 0.5
+	}
+	if cfg.Step <= 0 {
+		cfg.Step = 1
+	}
+	if cfg.Slow <= 1 {
+		cfg.Slow = 3
+	}
+	if cfg.MaxPause <= 0 {
+		cfg.MaxPause = 5 * time.Minute
+	}
+	return &Throttle{cfg: cfg, now: time.Now, hosts: map[string]*throttleHost{}}
+}
+
+// Rate returns the requests per second host is currently allowed. host
+// is a host:port, or a url; a bare host means its https port.
+func (t *Throttle) Rate(host string) float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if th, ok := t.hosts[throttleKey(host)]; ok {
+		return th.bucket.rate
+	}
+	return t.cfg.Start
+}
+
+// throttleKey is the key a host's state is kept under, its lower case
+// host:port.
+func throttleKey(host string) string {
+	if strings.Contains(host, "://") {
+		if u, err := url.Parse(host); err == nil {
+			return strings.ToLower(canonicalAddr(u))
+		}
+	}
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		host = net.JoinHostPort(strings.Trim(host, "[]"), "443")
+	}
+	return strings.ToLower(host)
+}
+
+// Rates returns the current rate of every host (host:port) the throttle
+// has seen lately. A host left alone for longer than MaxPause is
+// forgotten, and starts again at Start.
+func (t *Throttle) Rates() map[string]float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	rates := make(map[string]float64, len(t.hosts))
+	for host, th := range t.hosts {
+		rates[host] = th.bucket.rate
+	}
+	return rates
+}
+
+// host returns host's state; t.mu must be held.
+func (t *Throttle) host(host string) *throttleHost {
+	t.prune(t.now())
+	th, ok := t.hosts[host]
+	if !ok {
+		th = &throttleHost{bucket: newBucket(t.cfg.Start, 1)}
+		t.hosts[host] = th
+	}
+	return th
+}
+
+// prune drops the state of hosts that are no longer paused and haven't
+// been sent to for longer than MaxPause, at most every pruneEvery, so
+// hitting many hosts doesn't grow t.hosts for good; t.mu must be held.
+func (t *Throttle) prune(now time.Time) {
+	if now.Sub(t.pruned) < pruneEvery {
+		return
+	}
+	t.pruned = now
+	for host, th := range t.hosts {
+		if now.After(th.pausedUntil) && now.Sub(th.bucket.last) > t.cfg.MaxPause && th.bucket.idle(now) {
+			delete(t.hosts, host)
+		}
+	}
+}
+
+// paused returns how long host is held up by a Retry-After.
+func (t *Throttle) paused(host string) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.host(host).pausedUntil.Sub(t.now())
+}
+
+// take takes a token from host's bucket, returning how long to wait
+// for it and a func that hands it back.
+func (t *Throttle) take(host string) (time.Duration, func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	b := t.host(host).bucket
+	return b.take(t.now()), func() {
+		t.mu.Lock()
+		b.tokens++
+		t.mu.Unlock()
+	}
+}
+
+// observe adjusts host's rate to how a request went.
+func (t *Throttle) observe(host string, resp *http.Response, err error, latency time.Duration) {
+	t.mu.Lock()
+	th := t.host(host)
+	now := t.now()
+	old := th.bucket.rate
+	switch {
+	case err != nil:
+		t.cut(th, now)
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable:
+		t.cut(th, now)
+		if d, ok := parseRetryAfter(resp.Header.Get("Retry-After"), now); ok {
+			if d > t.cfg.MaxPause {
+				d = t.cfg.MaxPause
+			}
+			if until := now.Add(d); until.After(th.pausedUntil) {
+				th.pausedUntil = until
+			}
+		}
+	default:
+		slow := th.samples >= throttleLatencySamples && float64(latency) > t.cfg.Slow*float64(th.latency)
+		if th.samples == 0 {
+			th.latency = latency
+		} else {
+			th.latency += (latency - th.latency) / 10
+		}
+		th.samples++
+		if slow {
+			t.cut(th, now)
+			break
+		}
+		rate := th.bucket.rate + t.cfg.Step/th.bucket.rate
+		if rate > t.cfg.Max {
+			rate = t.cfg.Max
+		}
+		th.bucket.setRate(now, rate)
+	}
+	rate := th.bucket.rate
+	t.mu.Unlock()
+	if rate != old && t.cfg.OnChange != nil {
+		t.cfg.OnChange(host, rate)
+	}
+}
+
+// cut lowers th's rate, unless it was just lowered; t.mu must be held.
+func (t *Throttle) cut(th *throttleHost, now time.Time) {
+	if !th.lastCut.IsZero() && now.Sub(th.lastCut) < time.Second {
+		return
+	}
+	th.lastCut = now
+	rate := th.bucket.rate * t.cfg.Backoff
+	if rate < t.cfg.Min {
+		rate = t.cfg.Min
+	}
+	th.bucket.setRate(now, rate)
+}
+
+// parseRetryAfter parses a Retry-After value, either a number of
+// seconds or an http date, into how long from now to wait.
+func parseRetryAfter(v string, now time.Time) (time.Duration, bool) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	when, err := http.ParseTime(v)
+	if err != nil {
+		return 0, false
+	}
+	if d := when.Sub(now); d > 0 {
+		return d, true
+	}
+	return 0, true
+}
+
+// WithThrottle paces requests per host with t, slowing down when a
+// host shows signs of strain and speeding back up once it recovers.
+// Keep t to read the current rates. Combined with WithRateLimit, the
+// lower of the two rates applies.
+func WithThrottle(t *Throttle) optionClient {
+	return func(c *clientOptions) {
+		c.throttle = t
+	}
+}
+
+// throttleTransport implements WithThrottle.
+type throttleTransport struct {
+	next     http.RoundTripper
+	throttle *Throttle
+}
+
+func (t *throttleTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := strings.ToLower(canonicalAddr(req.URL))
+	ctx := req.Context()
+	for {
+		if d := t.throttle.paused(host); d > 0 {
+			if err := sleepCtx(ctx, d); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		wait, cancel := t.throttle.take(host)
+		if err := sleepCtx(ctx, wait); err != nil {
+			cancel()
+			return nil, err
+		}
+		// a Retry-After that came in while waiting holds this request
+		// up too. the token goes back, as another is taken after the
+		// pause.
+		if t.throttle.paused(host) <= 0 {
+			break
+		}
+		cancel()
+	}
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	if err != nil && (ctx.Err() != nil || errors.Is(err, context.Canceled)) {
+		// the caller gave up; that says nothing about the host.
+		return resp, err
+	}
+	t.throttle.observe(host, resp, err, time.Since(start))
+	return resp, err
+}
+
+func (t *throttleTransport) CloseIdleConnections() {
+	if c, ok := t.next.(interface{ CloseIdleConnections() }); ok {
+		c.CloseIdleConnections()
+	}
+}
+
+// sleepCtx waits for d, or until ctx is done.
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
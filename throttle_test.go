@@ -0,0 +1,244 @@
+package fuzzyHelpers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeClock lets a Throttle's notion of time be moved by hand.
+type fakeClock struct{ t time.Time }
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) now() time.Time      { return c.t }
+func (c *fakeClock) add(d time.Duration) { c.t = c.t.Add(d) }
+
+// status is a response with code and the header name, value pairs h.
+func status(code int, h ...string) *http.Response {
+	resp := &http.Response{StatusCode: code, Header: http.Header{}}
+	for i := 0; i+1 < len(h); i += 2 {
+		resp.Header.Set(h[i], h[i+1])
+	}
+	return resp
+}
+
+func TestThrottleSignals(t *testing.T) {
+	t.Parallel()
+	const host = "example.com:443"
+	tests := []struct {
+		name string
+		resp *http.Response
+		err  error
+		want float64
+	}{
+		{"ok", status(200), nil, 10.1},
+		{"too many requests", status(429), nil, 5},
+		{"unavailable", status(503), nil, 5},
+		{"server error", status(500), nil, 10.1},
+		{"connection error", nil, errors.New("connection reset"), 5},
+	}
+	for _, tt := range tests {
+		th := NewThrottle(ThrottleConfig{})
+		th.observe(host, tt.resp, tt.err, 10*time.Millisecond)
+		if got := th.Rate(host); got != tt.want {
+			t.Errorf("%s: rate %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestThrottleAdapts(t *testing.T) {
+	t.Parallel()
+	const host = "example.com:443"
+	clock := newFakeClock()
+	var changes []float64
+	th := NewThrottle(ThrottleConfig{Start: 8, Min: 1, Max: 9, OnChange: func(h string, rate float64) {
+		if h == host {
+			changes = append(changes, rate)
+		}
+	}})
+	th.now = clock.now
+
+	// a burst of 429s only cuts the rate once a second.
+	for i := 0; i < 5; i++ {
+		th.observe(host, status(429), nil, time.Millisecond)
+	}
+	if got := th.Rate(host); got != 4 {
+		t.Fatalf("rate after a burst of 429s = %v, want 4", got)
+	}
+	clock.add(time.Second)
+	th.observe(host, status(429), nil, time.Millisecond)
+	th.observe(host, nil, errors.New("refused"), time.Millisecond)
+	clock.add(time.Second)
+	th.observe(host, status(503), nil, time.Millisecond)
+	if got := th.Rate(host); got != 1 {
+		t.Fatalf("rate = %v, want the minimum, 1", got)
+	}
+
+	// recovering, it ramps back up, to the maximum.
+	for i := 0; i < 200; i++ {
+		clock.add(100 * time.Millisecond)
+		th.observe(host, status(200), nil, 10*time.Millisecond)
+	}
+	if got := th.Rate(host); got != 9 {
+		t.Fatalf("rate after recovering = %v, want the maximum, 9", got)
+	}
+
+	// responses far slower than usual count as trouble.
+	clock.add(time.Second)
+	th.observe(host, status(200), nil, 100*time.Millisecond)
+	if got := th.Rate(host); got != 4.5 {
+		t.Errorf("rate after a slow response = %v, want 4.5", got)
+	}
+
+	if len(changes) == 0 || changes[0] != 4 || changes[len(changes)-1] != 4.5 {
+		t.Errorf("OnChange saw %v", changes)
+	}
+	if rates := th.Rates(); len(rates) != 1 || rates[host] != 4.5 {
+		t.Errorf("Rates() = %v", rates)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		v    string
+		want time.Duration
+		ok   bool
+	}{
+		{"120", 2 * time.Minute, true},
+		{" 0 ", 0, true},
+		{"Mon, 01 Jan 2024 00:00:30 GMT", 30 * time.Second, true},
+		{"Sunday, 31-Dec-23 23:00:00 GMT", 0, true},
+		{"-5", 0, false},
+		{"soon", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseRetryAfter(tt.v, now)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseRetryAfter(%q) = %v, %v, want %v, %v", tt.v, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestThrottleRetryAfter(t *testing.T) {
+	t.Parallel()
+	var n int32
+	var mu sync.Mutex
+	var times []time.Time
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		times = append(times, time.Now())
+		mu.Unlock()
+		if atomic.AddInt32(&n, 1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer srv.Close()
+
+	th := NewThrottle(ThrottleConfig{Start: 100})
+	c := NewClient(WithThrottle(th))
+	for i := 0; i < 2; i++ {
+		resp, err := c.Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if gap := times[1].Sub(times[0]); gap < 900*time.Millisecond {
+		t.Errorf("second request went out %v after the 429, want about 1s", gap)
+	}
+	host := srv.Listener.Addr().String()
+	if got := th.Rate(host); got >= 100 {
+		t.Errorf("rate after the 429 = %v, want below 100", got)
+	}
+}
+
+func TestThrottleHostKey(t *testing.T) {
+	t.Parallel()
+	th := NewThrottle(ThrottleConfig{Start: 100})
+	rt := &throttleTransport{next: okTransport{}, throttle: th}
+	for _, u := range []string{"https://example.com/", "https://EXAMPLE.com:443/x"} {
+		req, _ := http.NewRequest(http.MethodGet, u, nil)
+		resp, err := rt.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	th.observe("example.com:443", status(429), nil, time.Millisecond)
+	if rates := th.Rates(); len(rates) != 1 || rates["example.com:443"] != 50 {
+		t.Errorf("Rates() = %v, want example.com:443 alone at 50", rates)
+	}
+	for _, host := range []string{"example.com", "Example.com:443", "https://example.com/"} {
+		if got := th.Rate(host); got != 50 {
+			t.Errorf("Rate(%q) = %v, want 50", host, got)
+		}
+	}
+	if got := th.Rate("http://example.com"); got != 100 {
+		t.Errorf("the http port shares the https port's rate, %v", got)
+	}
+}
+
+func TestThrottlePrune(t *testing.T) {
+	t.Parallel()
+	clock := newFakeClock()
+	th := NewThrottle(ThrottleConfig{MaxPause: time.Minute})
+	th.now = clock.now
+	for i := 0; i < 1000; i++ {
+		th.take(fmt.Sprintf("host%d.test:443", i))
+	}
+	clock.add(30 * time.Second)
+	th.take("slow.test:443")
+	th.observe("slow.test:443", status(http.StatusTooManyRequests, "Retry-After", "60"), nil, 0)
+	clock.add(45 * time.Second)
+	th.take("new.test:443")
+	// slow.test is still paused, so it keeps its rate.
+	if rates := th.Rates(); len(rates) != 2 || rates["slow.test:443"] != 5 {
+		t.Errorf("rates after the sweep %v, want slow.test:443 at 5 and new.test:443", rates)
+	}
+	clock.add(2 * time.Minute)
+	th.take("new.test:443")
+	if rates := th.Rates(); len(rates) != 1 {
+		t.Errorf("rates after the pause %v, want just new.test:443", rates)
+	}
+}
+
+func TestThrottlePauseWhileWaiting(t *testing.T) {
+	t.Parallel()
+	const host = "example.com:80"
+	th := NewThrottle(ThrottleConfig{Start: 2, Min: 2, Max: 2})
+	rt := &throttleTransport{next: okTransport{}, throttle: th}
+	// the bucket is empty, so the request waits 500ms for its token,
+	// and a pause until 600ms comes in meanwhile.
+	th.take(host)
+	time.AfterFunc(50*time.Millisecond, func() {
+		th.mu.Lock()
+		th.host(host).pausedUntil = time.Now().Add(550 * time.Millisecond)
+		th.mu.Unlock()
+	})
+	start := time.Now()
+	req, _ := http.NewRequest(http.MethodGet, "http://"+host+"/", nil)
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	// the token taken before the pause is handed back, so the one taken
+	// after it is already there.
+	if took := time.Since(start); took < 550*time.Millisecond || took > 850*time.Millisecond {
+		t.Errorf("request went out after %v, want about 600ms", took)
+	}
+}
@@ -0,0 +1,241 @@
+package fuzzyHelpers
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"strconv"
+	"strings"
+
+	utls "github.com/refraction-networking/utls"
+)
+
+// TLSFingerprint describes the ClientHello a browser sends. All values
+// are listed in the browser's own order, without GREASE; GREASE says
+// whether the browser mixes GREASE values in. KeyShares are the curves
+// a key share is sent for, the first of Curves if empty.
+type TLSFingerprint struct {
+	CipherSuites        []uint16
+	Extensions          []uint16
+	Curves              []tls.CurveID
+	KeyShares           []tls.CurveID
+	PointFormats        []uint8
+	SignatureAlgorithms []tls.SignatureScheme
+	ALPN                []string
+	MinVersion          uint16
+	MaxVersion          uint16
+	GREASE              bool
+}
+
+// JA3 returns the fingerprint's JA3 string.
+func (fp *TLSFingerprint) JA3() string {
+	join := func(n int, get func(int) int) string {
+		parts := make([]string, n)
+		for i := range parts {
+			parts[i] = strconv.Itoa(get(i))
+		}
+		return strings.Join(parts, "-")
+	}
+	return strings.Join([]string{
+		strconv.Itoa(int(tls.VersionTLS12)),
+		join(len(fp.CipherSuites), func(i int) int { return int(fp.CipherSuites[i]) }),
+		join(len(fp.Extensions), func(i int) int { return int(fp.Extensions[i]) }),
+		join(len(fp.Curves), func(i int) int { return int(fp.Curves[i]) }),
+		join(len(fp.PointFormats), func(i int) int { return int(fp.PointFormats[i]) }),
+	}, ",")
+}
+
+// TLSHandshaker runs the client side of a tls handshake over conn,
+// presenting fp (nil means no particular fingerprint). It returns the
+// connection to use and the negotiated ALPN protocol. cfg already has
+// ServerName, InsecureSkipVerify and NextProtos set.
+//
+// The default handshaker writes fp's ClientHello as is with utls: its
+// cipher suites, extensions, curves, point formats, signature
+// algorithms, ALPN and versions, in order, with GREASE if fp has it.
+// Without a fingerprint it's a plain crypto/tls handshake.
+type TLSHandshaker func(ctx context.Context, conn net.Conn, cfg *tls.Config, fp *TLSFingerprint) (net.Conn, string, error)
+
+// stdHandshake is the default TLSHandshaker.
+func stdHandshake(ctx context.Context, conn net.Conn, cfg *tls.Config, fp *TLSFingerprint) (net.Conn, string, error) {
+	if fp != nil {
+		return utlsHandshake(ctx, conn, cfg, fp)
+	}
+	tc := tls.Client(conn, cfg)
+	if err := handshake(ctx, tc); err != nil {
+		return nil, "", err
+	}
+	return tc, tc.ConnectionState().NegotiatedProtocol, nil
+}
+
+// utlsHandshake does the handshake with fp's ClientHello.
+func utlsHandshake(ctx context.Context, conn net.Conn, cfg *tls.Config, fp *TLSFingerprint) (net.Conn, string, error) {
+	uc := utls.UClient(conn, &utls.Config{
+		ServerName:         cfg.ServerName,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+		RootCAs:            cfg.RootCAs,
+		KeyLogWriter:       cfg.KeyLogWriter,
+		Time:               cfg.Time,
+	}, utls.HelloCustom)
+	if err := uc.ApplyPreset(fp.spec()); err != nil {
+		return nil, "", err
+	}
+	if err := handshake(ctx, uc); err != nil {
+		return nil, "", err
+	}
+	return uc, uc.ConnectionState().NegotiatedProtocol, nil
+}
+
+// spec builds the utls ClientHello for fp. Extensions utls has no type
+// for are sent empty.
+func (fp *TLSFingerprint) spec() *utls.ClientHelloSpec {
+	minVersion, maxVersion := fp.MinVersion, fp.MaxVersion
+	if minVersion == 0 {
+		minVersion = tls.VersionTLS12
+	}
+	if maxVersion == 0 {
+		maxVersion = tls.VersionTLS13
+	}
+	var suites []uint16
+	var curves []utls.CurveID
+	var shares []utls.KeyShare
+	var versions []uint16
+	if fp.GREASE {
+		suites = append(suites, utls.GREASE_PLACEHOLDER)
+		curves = append(curves, utls.GREASE_PLACEHOLDER)
+		shares = append(shares, utls.KeyShare{Group: utls.GREASE_PLACEHOLDER, Data: []byte{0}})
+		versions = append(versions, utls.GREASE_PLACEHOLDER)
+	}
+	suites = append(suites, fp.CipherSuites...)
+	for _, c := range fp.Curves {
+		curves = append(curves, utls.CurveID(c))
+	}
+	keyShares := fp.KeyShares
+	if len(keyShares) == 0 && len(fp.Curves) > 0 {
+		keyShares = fp.Curves[:1]
+	}
+	for _, c := range keyShares {
+		shares = append(shares, utls.KeyShare{Group: utls.CurveID(c)})
+	}
+	for v := maxVersion; v >= minVersion; v-- {
+		versions = append(versions, v)
+	}
+	sigAlgs := make([]utls.SignatureScheme, len(fp.SignatureAlgorithms))
+	for i, s := range fp.SignatureAlgorithms {
+		sigAlgs[i] = utls.SignatureScheme(s)
+	}
+
+	var exts []utls.TLSExtension
+	if fp.GREASE {
+		exts = append(exts, &utls.UtlsGREASEExtension{})
+	}
+	for i, id := range fp.Extensions {
+		var ext utls.TLSExtension
+		switch id {
+		case 0:
+			ext = &utls.SNIExtension{}
+		case 5:
+			ext = &utls.StatusRequestExtension{}
+		case 10:
+			ext = &utls.SupportedCurvesExtension{Curves: curves}
+		case 11:
+			ext = &utls.SupportedPointsExtension{SupportedPoints: fp.PointFormats}
+		case 13:
+			ext = &utls.SignatureAlgorithmsExtension{SupportedSignatureAlgorithms: sigAlgs}
+		case 16:
+			ext = &utls.ALPNExtension{AlpnProtocols: fp.ALPN}
+		case 18:
+			ext = &utls.SCTExtension{}
+		case 21:
+			if fp.GREASE && i == len(fp.Extensions)-1 {
+				// chrome's second GREASE extension goes just before the padding.
+				exts = append(exts, &utls.UtlsGREASEExtension{Body: []byte{0}})
+			}
+			// always padded, so the extension list is the same on every hello.
+			ext = &utls.UtlsPaddingExtension{GetPaddingLen: func(n int) (int, bool) {
+				l, _ := utls.BoringPaddingStyle(n)
+				return l, true
+			}}
+		case 23:
+			ext = &utls.ExtendedMasterSecretExtension{}
+		case 27:
+			ext = &utls.UtlsCompressCertExtension{Algorithms: []utls.CertCompressionAlgo{utls.CertCompressionBrotli}}
+		case 28:
+			ext = &utls.FakeRecordSizeLimitExtension{Limit: 0x4001}
+		case 34:
+			ext = &utls.FakeDelegatedCredentialsExtension{SupportedSignatureAlgorithms: []utls.SignatureScheme{
+				utls.ECDSAWithP256AndSHA256, utls.ECDSAWithP384AndSHA384, utls.ECDSAWithP521AndSHA512, utls.ECDSAWithSHA1,
+			}}
+		case 35:
+			ext = &utls.SessionTicketExtension{}
+		case 43:
+			ext = &utls.SupportedVersionsExtension{Versions: versions}
+		case 45:
+			ext = &utls.PSKKeyExchangeModesExtension{Modes: []uint8{utls.PskModeDHE}}
+		case 51:
+			ext = &utls.KeyShareExtension{KeyShares: shares}
+		case 17513:
+			ext = &utls.ApplicationSettingsExtension{SupportedProtocols: []string{"h2"}}
+		case 65281:
+			ext = &utls.RenegotiationInfoExtension{Renegotiation: utls.RenegotiateOnceAsClient}
+		default:
+			ext = &utls.GenericExtension{Id: id}
+		}
+		exts = append(exts, ext)
+	}
+	if fp.GREASE && (len(fp.Extensions) == 0 || fp.Extensions[len(fp.Extensions)-1] != 21) {
+		exts = append(exts, &utls.UtlsGREASEExtension{Body: []byte{0}})
+	}
+	return &utls.ClientHelloSpec{
+		CipherSuites:       suites,
+		CompressionMethods: []uint8{0},
+		Extensions:         exts,
+		TLSVersMin:         minVersion,
+		TLSVersMax:         maxVersion,
+	}
+}
+
+// chromiumTLS is the ClientHello of chrome 110+ and the browsers built
+// on it. chrome shuffles its extensions on every connection, so the
+// order here is just one of many.
+var chromiumTLS = &TLSFingerprint{
+	CipherSuites: []uint16{
+		0x1301, 0x1302, 0x1303,
+		0xc02b, 0xc02f, 0xc02c, 0xc030, 0xcca9, 0xcca8,
+		0xc013, 0xc014, 0x009c, 0x009d, 0x002f, 0x0035,
+	},
+	Extensions:   []uint16{0, 23, 65281, 10, 11, 35, 16, 5, 13, 18, 51, 45, 43, 27, 17513, 21},
+	Curves:       []tls.CurveID{tls.X25519, tls.CurveP256, tls.CurveP384},
+	PointFormats: []uint8{0},
+	SignatureAlgorithms: []tls.SignatureScheme{
+		tls.ECDSAWithP256AndSHA256, tls.PSSWithSHA256, tls.PKCS1WithSHA256,
+		tls.ECDSAWithP384AndSHA384, tls.PSSWithSHA384, tls.PKCS1WithSHA384,
+		tls.PSSWithSHA512, tls.PKCS1WithSHA512,
+	},
+	ALPN:       []string{"h2", "http/1.1"},
+	MinVersion: tls.VersionTLS12,
+	MaxVersion: tls.VersionTLS13,
+	GREASE:     true,
+}
+
+// firefoxTLS is the ClientHello of firefox 110.
+var firefoxTLS = &TLSFingerprint{
+	CipherSuites: []uint16{
+		0x1301, 0x1303, 0x1302,
+		0xc02b, 0xc02f, 0xcca9, 0xcca8, 0xc02c, 0xc030,
+		0xc00a, 0xc009, 0xc013, 0xc014, 0x009c, 0x009d, 0x002f, 0x0035,
+	},
+	Extensions:   []uint16{0, 23, 65281, 10, 11, 35, 16, 5, 34, 51, 43, 13, 45, 28, 21},
+	Curves:       []tls.CurveID{tls.X25519, tls.CurveP256, tls.CurveP384, tls.CurveP521, 256, 257},
+	KeyShares:    []tls.CurveID{tls.X25519, tls.CurveP256},
+	PointFormats: []uint8{0},
+	SignatureAlgorithms: []tls.SignatureScheme{
+		tls.ECDSAWithP256AndSHA256, tls.ECDSAWithP384AndSHA384, tls.ECDSAWithP521AndSHA512,
+		tls.PSSWithSHA256, tls.PSSWithSHA384, tls.PSSWithSHA512,
+		tls.PKCS1WithSHA256, tls.PKCS1WithSHA384, tls.PKCS1WithSHA512,
+		0x0203, tls.PKCS1WithSHA1,
+	},
+	ALPN:       []string{"h2", "http/1.1"},
+	MinVersion: tls.VersionTLS12,
+	MaxVersion: tls.VersionTLS13,
+}
@@ -0,0 +1,232 @@
+package fuzzyHelpers
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// helloRecorder is a tls http/1.1 server that records every ClientHello.
+type helloRecorder struct {
+	ln     net.Listener
+	mu     sync.Mutex
+	hellos []*tls.ClientHelloInfo
+}
+
+func newHelloRecorder(t *testing.T) *helloRecorder {
+	t.Helper()
+	ts := httptest.NewUnstartedServer(nil)
+	ts.StartTLS()
+	cfg := ts.TLS.Clone()
+	ts.Close()
+	r := &helloRecorder{}
+	cfg.NextProtos = []string{"http/1.1"}
+	cfg.GetConfigForClient = func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+		r.mu.Lock()
+		r.hellos = append(r.hellos, hello)
+		r.mu.Unlock()
+		return nil, nil
+	}
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.ln = ln
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				br := bufio.NewReader(c)
+				for {
+					req, err := http.ReadRequest(br)
+					if err != nil {
+						return
+					}
+					io.Copy(io.Discard, req.Body)
+					io.WriteString(c, "HTTP/1.1 200 OK\r\nContent-Length: 2\r\n\r\nok")
+				}
+			}()
+		}
+	}()
+	return r
+}
+
+func (r *helloRecorder) last(t *testing.T) *tls.ClientHelloInfo {
+	t.Helper()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.hellos) == 0 {
+		t.Fatal("no ClientHello recorded")
+	}
+	return r.hellos[len(r.hellos)-1]
+}
+
+func get(t *testing.T, c *http.Client, url string, headers http.Header) {
+	t.Helper()
+	req, _ := http.NewRequest("GET", url, nil)
+	req.Header = headers
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+}
+
+func hasSuite(hello *tls.ClientHelloInfo, s uint16) bool {
+	for _, v := range hello.CipherSuites {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func hasCurve(hello *tls.ClientHelloInfo, c tls.CurveID) bool {
+	for _, v := range hello.SupportedCurves {
+		if v == c {
+			return true
+		}
+	}
+	return false
+}
+
+func isGREASE(v uint16) bool {
+	return v&0x0f0f == 0x0a0a && v>>8 == v&0xff
+}
+
+// recordedJA3 is the JA3 of hello, GREASE left out.
+func recordedJA3(hello *tls.ClientHelloInfo) string {
+	fp := &TLSFingerprint{PointFormats: hello.SupportedPoints}
+	for _, s := range hello.CipherSuites {
+		if !isGREASE(s) {
+			fp.CipherSuites = append(fp.CipherSuites, s)
+		}
+	}
+	for _, e := range hello.Extensions {
+		if !isGREASE(e) {
+			fp.Extensions = append(fp.Extensions, e)
+		}
+	}
+	for _, c := range hello.SupportedCurves {
+		if !isGREASE(uint16(c)) {
+			fp.Curves = append(fp.Curves, c)
+		}
+	}
+	return fp.JA3()
+}
+
+func TestTLSFingerprint(t *testing.T) {
+	t.Parallel()
+	r := newHelloRecorder(t)
+	_, port, _ := net.SplitHostPort(r.ln.Addr().String())
+	// by name, as there's no sni for an ip.
+	url := "https://localhost:" + port
+	for _, tt := range []struct {
+		profile string
+		fp      *TLSFingerprint
+	}{
+		{"chrome", chromiumTLS},
+		{"firefox", firefoxTLS},
+	} {
+		get(t, NewClient(WithTLSFingerprint(tt.profile)), url, NewHeaders().Headers())
+		hello := r.last(t)
+		if got, want := recordedJA3(hello), tt.fp.JA3(); got != want {
+			t.Errorf("%s: sent ja3\n%s\nwant\n%s", tt.profile, got, want)
+		}
+		if greased := isGREASE(hello.CipherSuites[0]); greased != tt.fp.GREASE {
+			t.Errorf("%s: GREASE is %v, want %v", tt.profile, greased, tt.fp.GREASE)
+		}
+		if strings.Join(hello.SupportedProtos, ",") != "h2,http/1.1" {
+			t.Errorf("%s: got alpn %v want [h2 http/1.1]", tt.profile, hello.SupportedProtos)
+		}
+		if hello.ServerName != "localhost" {
+			t.Errorf("%s: got sni %q", tt.profile, hello.ServerName)
+		}
+	}
+
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Proto)
+	}))
+	ts.EnableHTTP2 = true
+	ts.StartTLS()
+	defer ts.Close()
+	for _, profile := range []string{"chrome", "firefox"} {
+		resp, err := NewClient(WithTLSFingerprint(profile)).Get(ts.URL)
+		if err != nil {
+			t.Fatalf("%s: %v", profile, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(body) != "HTTP/2.0" {
+			t.Errorf("%s: server saw %s, want HTTP/2.0", profile, body)
+		}
+	}
+}
+
+func TestTLSFingerprintAuto(t *testing.T) {
+	t.Parallel()
+	r := newHelloRecorder(t)
+	url := "https://" + r.ln.Addr().String()
+	c := NewClient(
+		WithTLSFingerprint("auto"),
+	)
+	get(t, c, url, NewHeaders(FirefoxOnly(true)).Headers())
+	hello := r.last(t)
+	if !hasSuite(hello, 0xc00a) || !hasCurve(hello, tls.CurveP521) {
+		t.Errorf("wanted firefox hello, got suites %x curves %v", hello.CipherSuites, hello.SupportedCurves)
+	}
+	get(t, c, url, NewHeaders(ChromeOnly(true)).Headers())
+	hello = r.last(t)
+	if hasSuite(hello, 0xc00a) || hasCurve(hello, tls.CurveP521) {
+		t.Errorf("wanted chrome hello, got suites %x curves %v", hello.CipherSuites, hello.SupportedCurves)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.hellos) != 2 {
+		t.Errorf("got %d handshakes, wanted a new connection per fingerprint", len(r.hellos))
+	}
+}
+
+func TestTLSHandshaker(t *testing.T) {
+	t.Parallel()
+	r := newHelloRecorder(t)
+	var got *TLSFingerprint
+	c := NewClient(
+		WithTLSFingerprint("firefox"),
+		WithTLSHandshaker(func(ctx context.Context, conn net.Conn, cfg *tls.Config, fp *TLSFingerprint) (net.Conn, string, error) {
+			got = fp
+			return stdHandshake(ctx, conn, cfg, fp)
+		}),
+	)
+	get(t, c, "https://"+r.ln.Addr().String(), NewHeaders().Headers())
+	if got != firefoxTLS {
+		t.Errorf("handshaker got fingerprint %v want firefox's", got)
+	}
+}
+
+func TestJA3(t *testing.T) {
+	t.Parallel()
+	fp := &TLSFingerprint{
+		CipherSuites: []uint16{0x1301, 0xc02b},
+		Extensions:   []uint16{0, 10, 11},
+		Curves:       []tls.CurveID{tls.X25519, tls.CurveP256},
+		PointFormats: []uint8{0},
+	}
+	want := "771,4865-49195,0-10-11,29-23,0"
+	if got := fp.JA3(); got != want {
+		t.Errorf("got %s want %s", got, want)
+	}
+}
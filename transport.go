@@ -0,0 +1,727 @@
+package fuzzyHelpers
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultDialTimeout = 30 * time.Second
+
+// headerNewlines strips characters that would let a value split into
+// a second header line.
+var headerNewlines = strings.NewReplacer("\r", "", "\n", "")
+
+// orderedTransport is an http.RoundTripper that writes requests itself so
+// headers go out in the order (and spelling) of the browser profile that
+// generated them. net/http sorts headers alphabetically, which bot
+// managers notice. It speaks HTTP/1.1, and HTTP/2 when the server offers
+// it via ALPN.
+type orderedTransport struct {
+	dialContext   func(ctx context.Context, network, addr string) (net.Conn, error)
+	proxy         func(*http.Request) (*url.URL, error)
+	connectHeader http.Header
+	tlsConfig     *tls.Config
+	handshake     TLSHandshaker
+	fingerprint   string
+	h2Print       string
+	maxIdle       int
+	// maxConns and the timeouts are the http.Transport's, 0 meaning no
+	// limit.
+	maxConns      int
+	idleTimeout   time.Duration
+	tlsTimeout    time.Duration
+	headerTimeout time.Duration
+
+	mu   sync.Mutex
+	idle map[string][]*h1Conn
+	h2   map[string]*h2Conn
+	// conns counts the open connections per key, http2 ones included.
+	// connWait is closed to wake the requests waiting for one to free
+	// up or become idle.
+	conns    map[string]int
+	connWait map[string]chan struct{}
+}
+
+func newOrderedTransport(tr *http.Transport, c *clientOptions) *orderedTransport {
+	cfg := tr.TLSClientConfig.Clone()
+	if cfg == nil {
+		cfg = &tls.Config{}
+	}
+	cfg.NextProtos = []string{"h2", "http/1.1"}
+	maxIdle := tr.MaxIdleConnsPerHost
+	if maxIdle <= 0 {
+		maxIdle = http.DefaultMaxIdleConnsPerHost
+	}
+	handshake := c.handshaker
+	if handshake == nil {
+		handshake = stdHandshake
+	}
+	dial := tr.DialContext
+	if dial == nil {
+		dial = (&net.Dialer{Timeout: defaultDialTimeout, KeepAlive: defaultDialTimeout}).DialContext
+	}
+	return &orderedTransport{
+		dialContext:   dial,
+		proxy:         tr.Proxy,
+		connectHeader: tr.ProxyConnectHeader,
+		tlsConfig:     cfg,
+		handshake:     handshake,
+		fingerprint:   c.fingerprint,
+		h2Print:       c.h2Fingerprint,
+		maxIdle:       maxIdle,
+		maxConns:      tr.MaxConnsPerHost,
+		idleTimeout:   tr.IdleConnTimeout,
+		tlsTimeout:    tr.TLSHandshakeTimeout,
+		headerTimeout: tr.ResponseHeaderTimeout,
+		idle:          map[string][]*h1Conn{},
+		h2:            map[string]*h2Conn{},
+		conns:         map[string]int{},
+		connWait:      map[string]chan struct{}{},
+	}
+}
+
+// fingerprintProfile resolves a fingerprint setting for req: "" is
+// none, "auto" is the profile that generated its headers, anything else
+// a profile name.
+func fingerprintProfile(setting string, req *http.Request) *Profile {
+	switch setting {
+	case "":
+		return nil
+	case "auto":
+		return matchProfile(req.Header)
+	}
+	p, _ := LookupProfile(setting)
+	return p
+}
+
+// tlsFingerprint returns the ClientHello to present for req, and a name
+// for it so connections with different fingerprints aren't mixed up.
+func (t *orderedTransport) tlsFingerprint(req *http.Request) (string, *TLSFingerprint) {
+	p := fingerprintProfile(t.fingerprint, req)
+	if p == nil || p.TLS == nil {
+		return "", nil
+	}
+	return p.Name, p.TLS
+}
+
+// http2Fingerprint is tlsFingerprint for the http2 connection preface.
+func (t *orderedTransport) http2Fingerprint(req *http.Request) (string, *HTTP2Fingerprint) {
+	p := fingerprintProfile(t.h2Print, req)
+	if p == nil || p.HTTP2 == nil {
+		return "", nil
+	}
+	return p.Name, p.HTTP2
+}
+
+func (t *orderedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL == nil {
+		closeBody(req)
+		return nil, errors.New("http: nil Request.URL")
+	}
+	if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+		closeBody(req)
+		return nil, fmt.Errorf("unsupported protocol scheme %q", req.URL.Scheme)
+	}
+	var proxyURL *url.URL
+	if t.proxy != nil {
+		u, err := t.proxy(req)
+		if err != nil {
+			closeBody(req)
+			return nil, err
+		}
+		proxyURL = u
+	}
+	fpName, fp := t.tlsFingerprint(req)
+	key := connKey(req.URL, proxyURL)
+	if fpName != "" {
+		key += "|tls=" + fpName
+	}
+	h2Name, h2fp := t.http2Fingerprint(req)
+	if h2Name != "" {
+		key += "|h2=" + h2Name
+	}
+
+	for {
+		cc, pc, err := t.getConn(req.Context(), key)
+		if err != nil {
+			closeBody(req)
+			return nil, err
+		}
+		if cc != nil {
+			resp, err := cc.roundTrip(req)
+			if !errors.Is(err, errH2Unusable) {
+				return resp, err
+			}
+			continue
+		}
+		if pc == nil {
+			c, proto, err := t.dial(req.Context(), req.URL, proxyURL, fp)
+			if err != nil {
+				t.connClosed(key)
+				closeBody(req)
+				return nil, err
+			}
+			if proto == "h2" {
+				cc, err := newH2Conn(c, h2fp, h2Options{
+					idleTimeout:   t.idleTimeout,
+					headerTimeout: t.headerTimeout,
+					onClose:       func() { t.connClosed(key) },
+				})
+				if err != nil {
+					t.connClosed(key)
+					closeBody(req)
+					return nil, err
+				}
+				t.putH2(key, cc)
+				return cc.roundTrip(req)
+			}
+			return t.newH1Conn(key, c, req.URL, proxyURL).roundTrip(req)
+		}
+		resp, err := pc.roundTrip(req)
+		if err == nil || req.Context().Err() != nil || !replayable(req) {
+			return resp, err
+		}
+		// the server most likely closed the idle connection under us,
+		// so try again on a fresh one like net/http does.
+		if req.GetBody != nil {
+			body, gerr := req.GetBody()
+			if gerr != nil {
+				return nil, err
+			}
+			r2 := *req
+			r2.Body = body
+			req = &r2
+		}
+	}
+}
+
+func (t *orderedTransport) newH1Conn(key string, c net.Conn, u, proxyURL *url.URL) *h1Conn {
+	pc := &h1Conn{t: t, key: key, conn: c, br: bufio.NewReader(c)}
+	// plain http through an http proxy uses the absolute-form.
+	pc.absolute = proxyURL != nil && u.Scheme == "http" && (proxyURL.Scheme == "http" || proxyURL.Scheme == "https")
+	pc.proxyAuth = proxyAuth(proxyURL)
+	return pc
+}
+
+// replayable reports whether req can safely be sent again after failing
+// on a reused connection.
+func replayable(req *http.Request) bool {
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+	default:
+		return false
+	}
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// CloseIdleConnections lets http.Client.CloseIdleConnections reach us.
+func (t *orderedTransport) CloseIdleConnections() {
+	t.mu.Lock()
+	idle, h2 := t.idle, t.h2
+	t.idle = map[string][]*h1Conn{}
+	t.h2 = map[string]*h2Conn{}
+	t.mu.Unlock()
+	for _, conns := range idle {
+		for _, pc := range conns {
+			pc.close()
+		}
+	}
+	for _, cc := range h2 {
+		cc.close()
+	}
+}
+
+func connKey(u *url.URL, proxyURL *url.URL) string {
+	key := u.Scheme + "://" + canonicalAddr(u)
+	if proxyURL != nil {
+		key += "|" + proxyURL.String()
+	}
+	return key
+}
+
+// canonicalAddr returns u's host:port, filling in the default port.
+func canonicalAddr(u *url.URL) string {
+	port := u.Port()
+	if port == "" {
+		switch u.Scheme {
+		case "https":
+			port = "443"
+		case "socks5", "socks5h":
+			port = "1080"
+		default:
+			port = "80"
+		}
+	}
+	return net.JoinHostPort(u.Hostname(), port)
+}
+
+// getConn finds a connection for key: a pooled http2 one, else an idle
+// http/1.1 one, else the go ahead to dial a new one (both nil), which
+// counts towards maxConns from then on. At maxConns it waits for one of
+// those, or for ctx to be done.
+func (t *orderedTransport) getConn(ctx context.Context, key string) (*h2Conn, *h1Conn, error) {
+	for {
+		t.mu.Lock()
+		if cc := t.h2[key]; cc != nil {
+			if cc.usable() {
+				t.mu.Unlock()
+				return cc, nil, nil
+			}
+			delete(t.h2, key)
+		}
+		if conns := t.idle[key]; len(conns) > 0 {
+			pc := conns[len(conns)-1]
+			t.idle[key] = conns[:len(conns)-1]
+			if pc.idleTimer != nil {
+				pc.idleTimer.Stop()
+			}
+			t.mu.Unlock()
+			return nil, pc, nil
+		}
+		if t.maxConns <= 0 || t.conns[key] < t.maxConns {
+			t.conns[key]++
+			t.mu.Unlock()
+			return nil, nil, nil
+		}
+		wait, ok := t.connWait[key]
+		if !ok {
+			wait = make(chan struct{})
+			t.connWait[key] = wait
+		}
+		t.mu.Unlock()
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		}
+	}
+}
+
+// connClosed gives back the slot of a connection to key that closed,
+// or was never made.
+func (t *orderedTransport) connClosed(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.conns[key]--; t.conns[key] <= 0 {
+		delete(t.conns, key)
+	}
+	t.wake(key)
+}
+
+// wake wakes the requests waiting for a connection to key; t.mu must
+// be held.
+func (t *orderedTransport) wake(key string) {
+	if wait, ok := t.connWait[key]; ok {
+		close(wait)
+		delete(t.connWait, key)
+	}
+}
+
+func (t *orderedTransport) putH2(key string, cc *h2Conn) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if old := t.h2[key]; old != nil && old.usable() {
+		// another request raced us to it, keep both in play but only
+		// pool one of them.
+		return
+	}
+	t.h2[key] = cc
+	t.wake(key)
+}
+
+func (t *orderedTransport) putIdle(pc *h1Conn) {
+	t.mu.Lock()
+	if len(t.idle[pc.key]) >= t.maxIdle {
+		t.mu.Unlock()
+		pc.close()
+		return
+	}
+	t.idle[pc.key] = append(t.idle[pc.key], pc)
+	if t.idleTimeout > 0 {
+		pc.idleTimer = time.AfterFunc(t.idleTimeout, func() { t.expireIdle(pc) })
+	}
+	t.wake(pc.key)
+	t.mu.Unlock()
+}
+
+// expireIdle closes pc if it's still sitting in the idle pool.
+func (t *orderedTransport) expireIdle(pc *h1Conn) {
+	t.mu.Lock()
+	conns := t.idle[pc.key]
+	for i, c := range conns {
+		if c == pc {
+			t.idle[pc.key] = append(conns[:i:i], conns[i+1:]...)
+			t.mu.Unlock()
+			pc.close()
+			return
+		}
+	}
+	t.mu.Unlock()
+}
+
+// dial connects to u, directly or through proxyURL, and does the tls
+// handshake for https presenting fp. It returns the negotiated protocol.
+func (t *orderedTransport) dial(ctx context.Context, u *url.URL, proxyURL *url.URL, fp *TLSFingerprint) (net.Conn, string, error) {
+	addr := canonicalAddr(u)
+	var conn net.Conn
+	var err error
+	switch {
+	case proxyURL == nil:
+		conn, err = t.dialContext(ctx, "tcp", addr)
+	case proxyURL.Scheme == "http" || proxyURL.Scheme == "https":
+		conn, err = t.dialHTTPProxy(ctx, proxyURL, addr, u.Scheme == "https")
+	default:
+		err = fmt.Errorf("unsupported proxy scheme %q", proxyURL.Scheme)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	if u.Scheme != "https" {
+		return conn, "http/1.1", nil
+	}
+	cfg := t.tlsConfig.Clone()
+	if cfg.ServerName == "" {
+		cfg.ServerName = u.Hostname()
+	}
+	hctx := ctx
+	if t.tlsTimeout > 0 {
+		var cancel context.CancelFunc
+		hctx, cancel = context.WithTimeout(ctx, t.tlsTimeout)
+		defer cancel()
+	}
+	tc, proto, err := t.handshake(hctx, conn, cfg, fp)
+	if err != nil {
+		conn.Close()
+		if ctx.Err() == nil && hctx.Err() != nil {
+			err = errTLSHandshakeTimeout
+		}
+		return nil, "", err
+	}
+	return tc, proto, nil
+}
+
+// timeoutError is a net.Error that timed out, so WithRetry treats it
+// like other timeouts.
+type timeoutError struct{ msg string }
+
+func (e *timeoutError) Error() string   { return e.msg }
+func (e *timeoutError) Timeout() bool   { return true }
+func (e *timeoutError) Temporary() bool { return true }
+
+var (
+	errTLSHandshakeTimeout   = &timeoutError{"tls handshake timeout"}
+	errResponseHeaderTimeout = &timeoutError{"timeout awaiting response headers"}
+)
+
+// dialHTTPProxy connects to an http(s) proxy. When tunnel is set it
+// issues a CONNECT for addr, otherwise the connection is used to send
+// absolute-form requests to the proxy itself.
+func (t *orderedTransport) dialHTTPProxy(ctx context.Context, proxyURL *url.URL, addr string, tunnel bool) (net.Conn, error) {
+	conn, err := t.dialContext(ctx, "tcp", canonicalAddr(proxyURL))
+	if err != nil {
+		return nil, err
+	}
+	if conn, err = proxyTLS(ctx, conn, proxyURL, t.tlsConfig.InsecureSkipVerify); err != nil {
+		return nil, err
+	}
+	if !tunnel {
+		return conn, nil
+	}
+	if err := connectTunnel(ctx, conn, proxyURL, addr, t.connectHeader); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// proxyTLS does the tls handshake with an https proxy, and leaves
+// connections to other proxies alone. conn is closed if it fails.
+func proxyTLS(ctx context.Context, conn net.Conn, proxyURL *url.URL, insecure bool) (net.Conn, error) {
+	if proxyURL.Scheme != "https" {
+		return conn, nil
+	}
+	tc := tls.Client(conn, &tls.Config{
+		ServerName:         proxyURL.Hostname(),
+		InsecureSkipVerify: insecure,
+	})
+	if err := handshake(ctx, tc); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tc, nil
+}
+
+// connectTunnel asks the http proxy at the other end of conn to
+// connect it to addr, authenticating with the proxy url's user info
+// and sending header along with the CONNECT.
+func connectTunnel(ctx context.Context, conn net.Conn, proxyURL *url.URL, addr string, header http.Header) error {
+	stop := closeOnDone(ctx, conn)
+	defer stop()
+	var b strings.Builder
+	fmt.Fprintf(&b, "CONNECT %s HTTP/1.1\r\nHost: %s\r\n", addr, addr)
+	if auth := proxyAuth(proxyURL); auth != "" && header.Get("Proxy-Authorization") == "" {
+		fmt.Fprintf(&b, "Proxy-Authorization: %s\r\n", auth)
+	}
+	for k, vs := range header {
+		for _, v := range vs {
+			fmt.Fprintf(&b, "%s: %s\r\n", headerNewlines.Replace(k), headerNewlines.Replace(v))
+		}
+	}
+	b.WriteString("\r\n")
+	if _, err := io.WriteString(conn, b.String()); err != nil {
+		return err
+	}
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, &http.Request{Method: http.MethodConnect})
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("proxy %s refused CONNECT to %s: %s", proxyURL.Host, addr, resp.Status)
+	}
+	if br.Buffered() > 0 {
+		return errors.New("proxy sent data before the tunnel was established")
+	}
+	return nil
+}
+
+func handshake(ctx context.Context, tc interface {
+	Handshake() error
+	io.Closer
+}) error {
+	stop := closeOnDone(ctx, tc)
+	defer stop()
+	err := tc.Handshake()
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// closeOnDone closes c if ctx is cancelled before stop is called.
+func closeOnDone(ctx context.Context, c io.Closer) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			c.Close()
+		case <-done:
+		}
+	}()
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }
+}
+
+func proxyAuth(proxyURL *url.URL) string {
+	if proxyURL == nil || proxyURL.User == nil {
+		return ""
+	}
+	pass, _ := proxyURL.User.Password()
+	creds := proxyURL.User.Username() + ":" + pass
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(creds))
+}
+
+func closeBody(req *http.Request) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+}
+
+// requestHost is the host the request is for. Unlike net/http, a Host
+// set in the headers wins, so fuzzing the Host header works.
+func requestHost(req *http.Request) string {
+	if v := headerValue(req.Header, "Host"); v != "" {
+		return v
+	}
+	if req.Host != "" {
+		return req.Host
+	}
+	return req.URL.Host
+}
+
+// h1Conn is a single HTTP/1.1 connection.
+type h1Conn struct {
+	t         *orderedTransport
+	key       string
+	conn      net.Conn
+	br        *bufio.Reader
+	absolute  bool
+	proxyAuth string
+	// idleTimer closes the connection once it's been idle for the
+	// transport's idleTimeout; guarded by t.mu.
+	idleTimer *time.Timer
+	closeOnce sync.Once
+}
+
+// close closes the connection and gives its slot back to the transport.
+func (pc *h1Conn) close() {
+	pc.closeOnce.Do(func() {
+		pc.conn.Close()
+		pc.t.connClosed(pc.key)
+	})
+}
+
+func (pc *h1Conn) roundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	stop := closeOnDone(ctx, pc.conn)
+	fail := func(err error) (*http.Response, error) {
+		stop()
+		pc.close()
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		return nil, err
+	}
+
+	if err := pc.writeRequest(req); err != nil {
+		closeBody(req)
+		return fail(err)
+	}
+	var timer *time.Timer
+	if d := pc.t.headerTimeout; d > 0 {
+		timer = time.AfterFunc(d, func() { pc.conn.Close() })
+	}
+	resp, err := http.ReadResponse(pc.br, req)
+	for err == nil && resp.StatusCode >= 100 && resp.StatusCode < 200 && resp.StatusCode != http.StatusSwitchingProtocols {
+		// skip informational responses like 100 Continue and 103
+		// Early Hints.
+		resp, err = http.ReadResponse(pc.br, req)
+	}
+	if timer != nil && !timer.Stop() {
+		// the timer closed the connection, whatever was read.
+		return fail(errResponseHeaderTimeout)
+	}
+	if err != nil {
+		return fail(err)
+	}
+	keep := !resp.Close && !req.Close
+	resp.Body = &h1Body{rc: resp.Body, pc: pc, stop: stop, keep: keep}
+	return resp, nil
+}
+
+func (pc *h1Conn) writeRequest(req *http.Request) error {
+	p := matchProfile(req.Header)
+	target := req.URL.RequestURI()
+	if pc.absolute {
+		target = req.URL.String()
+	}
+	method := req.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	bw := bufio.NewWriter(pc.conn)
+	fmt.Fprintf(bw, "%s %s HTTP/1.1\r\n", method, target)
+	for _, f := range orderedFields(p, req.Header, requestHost(req)) {
+		fmt.Fprintf(bw, "%s: %s\r\n", f.Name, headerNewlines.Replace(f.Value))
+	}
+	if pc.proxyAuth != "" && headerValue(req.Header, "Proxy-Authorization") == "" {
+		fmt.Fprintf(bw, "Proxy-Authorization: %s\r\n", pc.proxyAuth)
+	}
+	chunked := false
+	if req.Body != nil && req.Body != http.NoBody {
+		switch {
+		case req.ContentLength > 0:
+			if headerValue(req.Header, "Content-Length") == "" {
+				fmt.Fprintf(bw, "Content-Length: %d\r\n", req.ContentLength)
+			}
+		default:
+			chunked = true
+			bw.WriteString("Transfer-Encoding: chunked\r\n")
+		}
+	} else if methodSendsLength(method) && headerValue(req.Header, "Content-Length") == "" {
+		bw.WriteString("Content-Length: 0\r\n")
+	}
+	bw.WriteString("\r\n")
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		if chunked {
+			cw := &chunkedWriter{w: bw}
+			if _, err = io.Copy(cw, req.Body); err == nil {
+				_, err = bw.WriteString("0\r\n\r\n")
+			}
+		} else {
+			_, err = io.Copy(bw, req.Body)
+		}
+		req.Body.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// methodSendsLength reports whether browsers send Content-Length: 0 for
+// a bodiless request with this method.
+func methodSendsLength(method string) bool {
+	return method == http.MethodPost || method == http.MethodPut || method == http.MethodPatch
+}
+
+type chunkedWriter struct {
+	w io.Writer
+}
+
+func (cw *chunkedWriter) Write(b []byte) (int, error) {
+	if len(b) == 0 {
+		return 0, nil
+	}
+	if _, err := fmt.Fprintf(cw.w, "%x\r\n", len(b)); err != nil {
+		return 0, err
+	}
+	n, err := cw.w.Write(b)
+	if err != nil {
+		return n, err
+	}
+	_, err = io.WriteString(cw.w, "\r\n")
+	return n, err
+}
+
+// h1Body returns the connection to the idle pool once the body has been
+// read to the end, and closes it if the body is abandoned.
+type h1Body struct {
+	rc   io.ReadCloser
+	pc   *h1Conn
+	stop func()
+	keep bool
+	once sync.Once
+}
+
+func (b *h1Body) Read(p []byte) (int, error) {
+	n, err := b.rc.Read(p)
+	if err == io.EOF {
+		b.release(b.keep)
+	} else if err != nil {
+		b.release(false)
+	}
+	return n, err
+}
+
+// Close reuses the connection if whatever is left of the body is small
+// enough to drain, which covers the common read-nothing-and-close case.
+func (b *h1Body) Close() error {
+	_, err := io.CopyN(io.Discard, b.rc, 4<<10)
+	b.release(err == io.EOF && b.keep)
+	return b.rc.Close()
+}
+
+func (b *h1Body) release(reuse bool) {
+	b.once.Do(func() {
+		b.stop()
+		if reuse {
+			b.pc.t.putIdle(b.pc)
+			return
+		}
+		b.pc.close()
+	})
+}
@@ -0,0 +1,745 @@
+package fuzzyHelpers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+)
+
+// rawServer is a bare-bones http/1.1 server that records the header lines
+// of each request exactly as they arrived on the wire.
+type rawServer struct {
+	ln    net.Listener
+	mu    sync.Mutex
+	conns int
+	reqs  [][]string
+}
+
+func newRawServer(t *testing.T) *rawServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &rawServer{ln: ln}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.conns++
+			s.mu.Unlock()
+			go s.serve(c)
+		}
+	}()
+	return s
+}
+
+func (s *rawServer) serve(c net.Conn) {
+	defer c.Close()
+	br := bufio.NewReader(c)
+	for {
+		var lines []string
+		for {
+			line, err := br.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			if line == "" {
+				break
+			}
+			lines = append(lines, line)
+		}
+		s.mu.Lock()
+		s.reqs = append(s.reqs, lines)
+		s.mu.Unlock()
+		io.WriteString(c, "HTTP/1.1 200 OK\r\nContent-Length: 2\r\n\r\nok")
+	}
+}
+
+func (s *rawServer) url() string {
+	return "http://" + s.ln.Addr().String() + "/foo?bar=baz"
+}
+
+func headerNames(lines []string) []string {
+	var names []string
+	for _, l := range lines {
+		if i := strings.Index(l, ":"); i > 0 {
+			names = append(names, l[:i])
+		}
+	}
+	return names
+}
+
+func templateNames(t *testing.T, profile string, headers http.Header) []string {
+	t.Helper()
+	p, _ := LookupProfile(profile)
+	var names []string
+	for _, f := range p.Template {
+		if _, ok := headers[f.Name]; ok {
+			names = append(names, f.Name)
+		}
+	}
+	return names
+}
+
+func TestOrderedHTTP1(t *testing.T) {
+	t.Parallel()
+	s := newRawServer(t)
+	c := NewClient(
+		WithOrderedHeaders(true),
+	)
+	for _, profile := range []string{"chrome", "firefox", "safari"} {
+		h := NewHeaders(
+			WithProfile(profile),
+			WithOS("m"),
+		)
+		headers := h.Headers()
+		req, err := http.NewRequest("GET", s.url(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header = headers
+		resp, err := c.Do(req)
+		if err != nil {
+			t.Fatalf("%s: %v", profile, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(body) != "ok" {
+			t.Errorf("%s: got body %q want %q", profile, body, "ok")
+		}
+
+		s.mu.Lock()
+		lines := s.reqs[len(s.reqs)-1]
+		s.mu.Unlock()
+		if lines[0] != "GET /foo?bar=baz HTTP/1.1" {
+			t.Errorf("%s: got request line %q", profile, lines[0])
+		}
+		want := append([]string{"Host"}, templateNames(t, profile, headers)...)
+		got := headerNames(lines[1:])
+		if strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Errorf("%s: got header order\n%v\nwant\n%v", profile, got, want)
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conns != 1 {
+		t.Errorf("got %d connections, wanted the first to be reused", s.conns)
+	}
+}
+
+func TestOrderedHTTP1UnknownHeadersLast(t *testing.T) {
+	t.Parallel()
+	s := newRawServer(t)
+	c := NewClient(
+		WithOrderedHeaders(true),
+	)
+	h := NewHeaders(
+		FirefoxOnly(true),
+		WithCustomHeaders("X-Zed=1 x-alpha=2"),
+	)
+	req, _ := http.NewRequest("POST", s.url(), strings.NewReader("a=b"))
+	req.Header = h.Headers()
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	s.mu.Lock()
+	got := headerNames(s.reqs[0][1:])
+	s.mu.Unlock()
+	tail := strings.Join(got[len(got)-3:], ",")
+	if tail != "x-alpha,X-Zed,Content-Length" {
+		t.Errorf("got trailing headers %s", tail)
+	}
+}
+
+// h2Recorder is a tls http2 server that records the frames each client
+// sends, decoding the header blocks so their order can be checked. conns
+// holds a one-line summary of every frame, per connection.
+type h2Recorder struct {
+	ln     net.Listener
+	mu     sync.Mutex
+	conns  [][]string
+	fields [][]hpack.HeaderField
+}
+
+func newH2Recorder(t *testing.T) *h2Recorder {
+	t.Helper()
+	ts := httptest.NewUnstartedServer(nil)
+	ts.StartTLS()
+	cfg := ts.TLS.Clone()
+	ts.Close()
+	cfg.NextProtos = []string{"h2"}
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &h2Recorder{ln: ln}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go r.serve(c)
+		}
+	}()
+	return r
+}
+
+func (r *h2Recorder) serve(c net.Conn) {
+	defer c.Close()
+	preface := make([]byte, len(http2.ClientPreface))
+	if _, err := io.ReadFull(c, preface); err != nil {
+		return
+	}
+	fr := http2.NewFramer(c, c)
+	fr.ReadMetaHeaders = hpack.NewDecoder(4096, nil)
+	if fr.WriteSettings() != nil {
+		return
+	}
+	var hbuf bytes.Buffer
+	enc := hpack.NewEncoder(&hbuf)
+	r.mu.Lock()
+	conn := len(r.conns)
+	r.conns = append(r.conns, nil)
+	r.mu.Unlock()
+	for {
+		f, err := fr.ReadFrame()
+		if err != nil {
+			return
+		}
+		r.mu.Lock()
+		r.conns[conn] = append(r.conns[conn], summarizeFrame(f))
+		r.mu.Unlock()
+		switch f := f.(type) {
+		case *http2.SettingsFrame:
+			if !f.IsAck() {
+				fr.WriteSettingsAck()
+			}
+		case *http2.MetaHeadersFrame:
+			r.mu.Lock()
+			r.fields = append(r.fields, f.Fields)
+			r.mu.Unlock()
+			hbuf.Reset()
+			enc.WriteField(hpack.HeaderField{Name: ":status", Value: "200"})
+			enc.WriteField(hpack.HeaderField{Name: "content-length", Value: "2"})
+			fr.WriteHeaders(http2.HeadersFrameParam{
+				StreamID:      f.StreamID,
+				BlockFragment: hbuf.Bytes(),
+				EndHeaders:    true,
+			})
+			fr.WriteData(f.StreamID, true, []byte("ok"))
+		}
+	}
+}
+
+// summarizeFrame describes the parts of f that make up an http2
+// fingerprint, e.g. "SETTINGS 1:65536,4:131072".
+func summarizeFrame(f http2.Frame) string {
+	prio := func(p http2.PriorityParam) string {
+		return fmt.Sprintf("%d:%t:%d", p.StreamDep, p.Exclusive, p.Weight)
+	}
+	switch f := f.(type) {
+	case *http2.SettingsFrame:
+		var settings []string
+		f.ForeachSetting(func(s http2.Setting) error {
+			settings = append(settings, fmt.Sprintf("%d:%d", s.ID, s.Val))
+			return nil
+		})
+		if f.IsAck() {
+			return "SETTINGS ack"
+		}
+		return "SETTINGS " + strings.Join(settings, ",")
+	case *http2.WindowUpdateFrame:
+		return fmt.Sprintf("WINDOW_UPDATE %d %d", f.StreamID, f.Increment)
+	case *http2.PriorityFrame:
+		return fmt.Sprintf("PRIORITY %d %s", f.StreamID, prio(f.PriorityParam))
+	case *http2.MetaHeadersFrame:
+		s := fmt.Sprintf("HEADERS %d", f.StreamID)
+		if f.HasPriority() {
+			s += " " + prio(f.Priority)
+		}
+		return s
+	}
+	return fmt.Sprintf("%s %d", f.Header().Type, f.Header().StreamID)
+}
+
+func (r *h2Recorder) url() string {
+	return "https://" + r.ln.Addr().String() + "/foo?bar=baz"
+}
+
+func TestOrderedHTTP2(t *testing.T) {
+	t.Parallel()
+	r := newH2Recorder(t)
+	c := NewClient(
+		WithOrderedHeaders(true),
+	)
+	tests := []struct {
+		profile string
+		pseudo  string
+	}{
+		{"chrome", ":method,:authority,:scheme,:path"},
+		{"firefox", ":method,:path,:authority,:scheme"},
+		{"safari", ":method,:scheme,:path,:authority"},
+	}
+	for i, tt := range tests {
+		h := NewHeaders(
+			WithProfile(tt.profile),
+			WithOS("m"),
+		)
+		headers := h.Headers()
+		req, _ := http.NewRequest("GET", r.url(), nil)
+		req.Header = headers
+		resp, err := c.Do(req)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.profile, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.Proto != "HTTP/2.0" || string(body) != "ok" {
+			t.Errorf("%s: got %s %q", tt.profile, resp.Proto, body)
+		}
+
+		r.mu.Lock()
+		fields := r.fields[i]
+		r.mu.Unlock()
+		var pseudo, regular []string
+		for _, f := range fields {
+			if f.IsPseudo() {
+				pseudo = append(pseudo, f.Name)
+				continue
+			}
+			regular = append(regular, f.Name)
+		}
+		if got := strings.Join(pseudo, ","); got != tt.pseudo {
+			t.Errorf("%s: got pseudo-headers %s want %s", tt.profile, got, tt.pseudo)
+		}
+		var want []string
+		for _, name := range templateNames(t, tt.profile, headers) {
+			if name != "Connection" {
+				want = append(want, strings.ToLower(name))
+			}
+		}
+		if strings.Join(regular, ",") != strings.Join(want, ",") {
+			t.Errorf("%s: got header order\n%v\nwant\n%v", tt.profile, regular, want)
+		}
+	}
+}
+
+func TestOrderedHTTP2RoundTrip(t *testing.T) {
+	t.Parallel()
+	big := bytes.Repeat([]byte("fuzzy"), 300<<10)
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			io.Copy(w, r.Body)
+			return
+		}
+		w.Write(big)
+	}))
+	ts.EnableHTTP2 = true
+	ts.StartTLS()
+	defer ts.Close()
+
+	c := NewClient(
+		WithOrderedHeaders(true),
+	)
+	t.Run("large response", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", ts.URL, nil)
+		req.Header = NewHeaders().Headers()
+		resp, err := c.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Proto != "HTTP/2.0" {
+			t.Errorf("got %s want HTTP/2.0", resp.Proto)
+		}
+		if !bytes.Equal(body, big) {
+			t.Errorf("got %d bytes want %d", len(body), len(big))
+		}
+	})
+	t.Run("large request body", func(t *testing.T) {
+		payload := big[:200<<10]
+		req, _ := http.NewRequest("POST", ts.URL, bytes.NewReader(payload))
+		req.Header = NewHeaders().Headers()
+		resp, err := c.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		if !bytes.Equal(body, payload) {
+			t.Errorf("got %d bytes back want %d", len(body), len(payload))
+		}
+	})
+}
+
+func TestHTTP2Fingerprint(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		profile string
+		want    []string
+	}{
+		{"chrome", []string{
+			"SETTINGS 1:65536,2:0,4:6291456,6:262144",
+			"WINDOW_UPDATE 0 15663105",
+			"HEADERS 1 0:true:255",
+		}},
+		{"firefox", []string{
+			"SETTINGS 1:65536,4:131072,5:16384",
+			"WINDOW_UPDATE 0 12517377",
+			"PRIORITY 3 0:false:200",
+			"PRIORITY 5 0:false:100",
+			"PRIORITY 7 0:false:0",
+			"PRIORITY 9 7:false:0",
+			"PRIORITY 11 3:false:0",
+			"PRIORITY 13 0:false:240",
+			"HEADERS 15 13:false:41",
+		}},
+		{"safari", []string{
+			"SETTINGS 2:0,4:4194304,3:100",
+			"WINDOW_UPDATE 0 10485760",
+			"HEADERS 1 0:false:254",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.profile, func(t *testing.T) {
+			r := newH2Recorder(t)
+			c := NewClient(
+				WithHTTP2Fingerprint(tt.profile),
+			)
+			req, _ := http.NewRequest("GET", r.url(), nil)
+			resp, err := c.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			r.mu.Lock()
+			got := r.conns[0]
+			r.mu.Unlock()
+			var frames []string
+			for _, f := range got {
+				if f != "SETTINGS ack" {
+					frames = append(frames, f)
+				}
+			}
+			if strings.Join(frames, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("got frames\n%s\nwant\n%s", strings.Join(frames, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestHTTP2FingerprintAuto(t *testing.T) {
+	t.Parallel()
+	r := newH2Recorder(t)
+	c := NewClient(
+		WithHTTP2Fingerprint("auto"),
+	)
+	for _, profile := range []string{"chrome", "firefox", "chrome"} {
+		req, _ := http.NewRequest("GET", r.url(), nil)
+		req.Header = NewHeaders(WithProfile(profile), WithOS("w")).Headers()
+		resp, err := c.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.conns) != 2 {
+		t.Fatalf("got %d connections want one per fingerprint", len(r.conns))
+	}
+	if r.conns[0][0] == r.conns[1][0] {
+		t.Errorf("both connections sent %s", r.conns[0][0])
+	}
+}
+
+func TestAkamaiFingerprint(t *testing.T) {
+	p, _ := LookupProfile("firefox")
+	want := "1:65536;4:131072;5:16384|12517377|3:0:0:201,5:0:0:101,7:0:0:1,9:0:7:1,11:0:3:1,13:0:0:241|m,p,a,s"
+	if got := p.HTTP2.Akamai(p.PseudoHeaderOrder); got != want {
+		t.Errorf("got %s want %s", got, want)
+	}
+	p, _ = LookupProfile("chrome")
+	want = "1:65536;2:0;4:6291456;6:262144|15663105|0|m,a,s,p"
+	if got := p.HTTP2.Akamai(p.PseudoHeaderOrder); got != want {
+		t.Errorf("got %s want %s", got, want)
+	}
+}
+
+func TestOrderedConnectionLimit(t *testing.T) {
+	t.Parallel()
+	for _, h2 := range []bool{false, true} {
+		var mu sync.Mutex
+		var active, peak, conns int
+		ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			active++
+			if active > peak {
+				peak = active
+			}
+			mu.Unlock()
+			time.Sleep(20 * time.Millisecond)
+			mu.Lock()
+			active--
+			mu.Unlock()
+		}))
+		ts.Config.ConnState = func(c net.Conn, s http.ConnState) {
+			if s == http.StateNew {
+				mu.Lock()
+				conns++
+				mu.Unlock()
+			}
+		}
+		if h2 {
+			ts.EnableHTTP2 = true
+			ts.StartTLS()
+		} else {
+			ts.Start()
+		}
+		t.Cleanup(ts.Close)
+
+		c := NewClient(WithConnections(2), WithOrderedHeaders(true))
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				resp, err := c.Get(ts.URL)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				io.Copy(io.Discard, resp.Body)
+				resp.Body.Close()
+			}()
+		}
+		wg.Wait()
+		mu.Lock()
+		if conns > 2 {
+			t.Errorf("h2 %v: opened %d connections, want at most 2", h2, conns)
+		}
+		if !h2 && peak > 2 {
+			t.Errorf("served %d requests at once over http/1.1, want at most 2", peak)
+		}
+		mu.Unlock()
+	}
+}
+
+// orderedFor returns an ordered transport built from the default
+// transport, changed by edit.
+func orderedFor(edit func(tr *http.Transport)) *orderedTransport {
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	tr.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	edit(tr)
+	return newOrderedTransport(tr, &clientOptions{})
+}
+
+func TestOrderedHTTP2FlowControl(t *testing.T) {
+	t.Parallel()
+	// more than the default window of 4MB.
+	big := bytes.Repeat([]byte("fuzzy"), 2<<20)
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(big)
+	}))
+	ts.EnableHTTP2 = true
+	ts.StartTLS()
+	defer ts.Close()
+
+	req, _ := http.NewRequest("GET", ts.URL, nil)
+	resp, err := orderedFor(func(*http.Transport) {}).RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	// nothing's been read, so the server can't send more than a window.
+	time.Sleep(200 * time.Millisecond)
+	b := resp.Body.(*h2Body)
+	b.cc.mu.Lock()
+	buffered, window := b.cs.buf.Len(), int(b.cc.recvWindow)
+	b.cc.mu.Unlock()
+	if buffered > window {
+		t.Errorf("buffered %d bytes of an unread body, want at most the %d byte window", buffered, window)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(body, big) {
+		t.Errorf("got %d bytes want %d", len(body), len(big))
+	}
+}
+
+func TestOrderedHTTP2StreamLimit(t *testing.T) {
+	t.Parallel()
+	release := make(chan struct{})
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			w.WriteHeader(http.StatusOK)
+			w.(http.Flusher).Flush()
+			<-release
+		}
+	}))
+	if err := http2.ConfigureServer(ts.Config, &http2.Server{MaxConcurrentStreams: 1}); err != nil {
+		t.Fatal(err)
+	}
+	ts.TLS = &tls.Config{NextProtos: []string{"h2"}}
+	ts.StartTLS()
+	defer ts.Close()
+
+	rt := orderedFor(func(*http.Transport) {})
+	req, _ := http.NewRequest("GET", ts.URL+"/slow", nil)
+	slow, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the only stream is taken, so this one waits until it gives up.
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	req, _ = http.NewRequestWithContext(ctx, "GET", ts.URL+"/fast", nil)
+	start := time.Now()
+	if _, err := rt.RoundTrip(req); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v, want the context's error", err)
+	}
+	if took := time.Since(start); took > time.Second {
+		t.Errorf("waiting for a stream took %v after the context was done", took)
+	}
+	close(release)
+	io.Copy(io.Discard, slow.Body)
+	slow.Body.Close()
+}
+
+func TestOrderedHTTP2CancelFreesStream(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/hang" {
+			<-r.Context().Done()
+		}
+	}))
+	if err := http2.ConfigureServer(ts.Config, &http2.Server{MaxConcurrentStreams: 1}); err != nil {
+		t.Fatal(err)
+	}
+	ts.TLS = &tls.Config{NextProtos: []string{"h2"}}
+	ts.StartTLS()
+	defer ts.Close()
+
+	rt := orderedFor(func(*http.Transport) {})
+	for i := 0; i < 20; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		req, _ := http.NewRequestWithContext(ctx, "GET", ts.URL+"/hang", nil)
+		if _, err := rt.RoundTrip(req); err == nil {
+			t.Fatal("got a response from a hanging handler")
+		}
+		cancel()
+	}
+	rt.mu.Lock()
+	for _, cc := range rt.h2 {
+		cc.mu.Lock()
+		if n := len(cc.streams); n != 0 {
+			t.Errorf("%d cancelled streams still open", n)
+		}
+		cc.mu.Unlock()
+	}
+	rt.mu.Unlock()
+	// the one stream the server allows has to have been reset.
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", ts.URL+"/fast", nil)
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+}
+
+func TestOrderedTimeouts(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(300 * time.Millisecond)
+		}
+		io.WriteString(w, "ok")
+	}))
+	t.Cleanup(ts.Close)
+
+	t.Run("response headers", func(t *testing.T) {
+		rt := orderedFor(func(tr *http.Transport) { tr.ResponseHeaderTimeout = 50 * time.Millisecond })
+		req, _ := http.NewRequest("GET", ts.URL+"/slow", nil)
+		_, err := rt.RoundTrip(req)
+		var netErr net.Error
+		if !errors.As(err, &netErr) || !netErr.Timeout() {
+			t.Errorf("got %v, want a timeout", err)
+		}
+	})
+	t.Run("tls handshake", func(t *testing.T) {
+		// a server that never answers the ClientHello.
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer ln.Close()
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			<-done
+			c.Close()
+		}()
+		rt := orderedFor(func(tr *http.Transport) { tr.TLSHandshakeTimeout = 50 * time.Millisecond })
+		req, _ := http.NewRequest("GET", "https://"+ln.Addr().String()+"/", nil)
+		if _, err := rt.RoundTrip(req); err != errTLSHandshakeTimeout {
+			t.Errorf("got %v, want %v", err, errTLSHandshakeTimeout)
+		}
+	})
+	t.Run("idle connections", func(t *testing.T) {
+		rt := orderedFor(func(tr *http.Transport) { tr.IdleConnTimeout = 50 * time.Millisecond })
+		req, _ := http.NewRequest("GET", ts.URL, nil)
+		resp, err := rt.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		time.Sleep(200 * time.Millisecond)
+		rt.mu.Lock()
+		defer rt.mu.Unlock()
+		for key, conns := range rt.idle {
+			if len(conns) > 0 {
+				t.Errorf("%d connections to %s still idle after the timeout", len(conns), key)
+			}
+		}
+		if len(rt.conns) != 0 {
+			t.Errorf("still counting open connections %v", rt.conns)
+		}
+	})
+}
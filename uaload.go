@@ -0,0 +1,241 @@
+package fuzzyHelpers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// uaRecord is one entry of a JSON user agent list.
+type uaRecord struct {
+	Browser  string `json:"browser"`
+	OS       string `json:"os"`
+	Version  string `json:"version"`
+	Chromium string `json:"chromium"`
+	UA       string `json:"ua"`
+}
+
+// ReadUserAgents reads a list of user agents, grouped by the profile
+// they belong to. The list is either plain text, one ua per line (blank
+// lines and lines starting with # are skipped), or a JSON array of
+// objects:
+//
+//	[{"browser": "chrome", "os": "w", "version": "120.0.6099.109", "ua": "Mozilla/5.0 ..."}]
+//
+// browser is the name of a registered profile, os is one of "l", "m",
+// "w", "a", "i" (or "linux", "mac", "windows", "android", "ios"), and
+// chromium is the chromium version of browsers that version themselves
+// separately, as in UserAgent. Whatever is left out, and everything
+// for plain text lines, is worked out from the ua itself. A ua that
+// can't be placed is an error.
+func ReadUserAgents(r io.Reader) (map[string][]UserAgent, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var records []uaRecord
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &records); err != nil {
+			return nil, fmt.Errorf("parsing user agents: %w", err)
+		}
+	} else {
+		sc := bufio.NewScanner(bytes.NewReader(data))
+		sc.Buffer(nil, 1<<20)
+		for sc.Scan() {
+			line := strings.TrimSpace(sc.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			records = append(records, uaRecord{UA: line})
+		}
+		if err := sc.Err(); err != nil {
+			return nil, err
+		}
+	}
+	uas := map[string][]UserAgent{}
+	for i, rec := range records {
+		browser, ua, err := rec.userAgent()
+		if err != nil {
+			return nil, fmt.Errorf("user agent %d: %w", i+1, err)
+		}
+		uas[browser] = append(uas[browser], ua)
+	}
+	if len(uas) == 0 {
+		return nil, fmt.Errorf("no user agents found")
+	}
+	return uas, nil
+}
+
+// userAgent fills in what rec leaves out from its ua.
+func (rec uaRecord) userAgent() (string, UserAgent, error) {
+	value := strings.TrimSpace(rec.UA)
+	if value == "" {
+		return "", UserAgent{}, fmt.Errorf("no ua")
+	}
+	browser, ua, _ := parseUserAgent(value)
+	if rec.Browser != "" {
+		browser = strings.ToLower(strings.TrimSpace(rec.Browser))
+	}
+	if browser == "" {
+		return "", UserAgent{}, fmt.Errorf("unknown browser: %s", value)
+	}
+	if _, ok := LookupProfile(browser); !ok {
+		return "", UserAgent{}, fmt.Errorf("no profile named %s", browser)
+	}
+	if rec.OS != "" {
+		osys, ok := osNames[strings.ToLower(strings.TrimSpace(rec.OS))]
+		if !ok {
+			return "", UserAgent{}, fmt.Errorf("unknown os %q", rec.OS)
+		}
+		ua.OS = osys
+	}
+	if rec.Version != "" {
+		ua.Version = rec.Version
+	}
+	if rec.Chromium != "" {
+		ua.Chromium = rec.Chromium
+	}
+	if ua.OS == "" || ua.Version == "" {
+		return "", UserAgent{}, fmt.Errorf("can't tell the os and version of %s", value)
+	}
+	ua.Value = value
+	return browser, ua, nil
+}
+
+// osNames maps the os names ReadUserAgents accepts to WithOS values.
+var osNames = map[string]string{
+	"l": "l", "linux": "l",
+	"m": "m", "mac": "m", "macos": "m",
+	"w": "w", "windows": "w",
+	"a": "a", "android": "a",
+	"i": "i", "ios": "i",
+}
+
+var (
+	chromeToken  = regexp.MustCompile(`Chrome/([\d.]+)`)
+	edgeToken    = regexp.MustCompile(`Edg(?:A)?/([\d.]+)`)
+	operaToken   = regexp.MustCompile(`OPR/([\d.]+)`)
+	firefoxToken = regexp.MustCompile(`Firefox/([\d.]+)`)
+	safariToken  = regexp.MustCompile(`Version/([\d.]+).*Safari/`)
+)
+
+// parseUserAgent works out which built in browser, os and version a ua
+// string belongs to. ok is false if it doesn't look like any of them.
+// Chromium browsers only report their major version these days, so
+// Version is often "120.0.0.0" rather than the full one.
+func parseUserAgent(s string) (browser string, ua UserAgent, ok bool) {
+	ua.Value = s
+	switch {
+	case strings.Contains(s, "Android"):
+		ua.OS = "a"
+	case strings.Contains(s, "iPhone"), strings.Contains(s, "iPad"):
+		ua.OS = "i"
+	case strings.Contains(s, "Windows"):
+		ua.OS = "w"
+	case strings.Contains(s, "Macintosh"):
+		ua.OS = "m"
+	case strings.Contains(s, "Linux"), strings.Contains(s, "X11"):
+		ua.OS = "l"
+	}
+	chromium := ""
+	if m := chromeToken.FindStringSubmatch(s); m != nil {
+		chromium = m[1]
+	}
+	switch {
+	case strings.Contains(s, "CriOS/"), strings.Contains(s, "EdgiOS/"), strings.Contains(s, "FxiOS/"):
+		// webkit underneath, with headers of its own.
+		return "", ua, false
+	case edgeToken.MatchString(s) && chromium != "":
+		browser, ua.Version, ua.Chromium = "edge", edgeToken.FindStringSubmatch(s)[1], chromium
+	case operaToken.MatchString(s) && chromium != "":
+		browser, ua.Version, ua.Chromium = "opera", operaToken.FindStringSubmatch(s)[1], chromium
+	case chromium != "":
+		browser, ua.Version = "chrome", chromium
+	case firefoxToken.MatchString(s):
+		browser, ua.Version = "firefox", firefoxToken.FindStringSubmatch(s)[1]
+	case safariToken.MatchString(s):
+		browser, ua.Version = "safari", safariToken.FindStringSubmatch(s)[1]
+	default:
+		return "", ua, false
+	}
+	return browser, ua, ua.OS != ""
+}
+
+// WithUserAgents replaces the built in user agents of each profile r
+// has any for, read as by ReadUserAgents. Profiles r doesn't mention
+// keep theirs. If ReadUserAgents rejects the input, the built in user
+// agents are used and Err reports why.
+func WithUserAgents(r io.Reader) optionHeaders {
+	return func(h *headers) {
+		uas, err := ReadUserAgents(r)
+		if err != nil {
+			h.fail(fmt.Errorf("user agents: %w", err))
+			return
+		}
+		h.userAgents = uas
+	}
+}
+
+// WithUserAgentFile is WithUserAgents reading from the file at path. If
+// the file can't be read, the built in user agents are used and Err
+// reports why.
+func WithUserAgentFile(path string) optionHeaders {
+	return func(h *headers) {
+		f, err := os.Open(path)
+		if err != nil {
+			h.fail(fmt.Errorf("user agents: %w", err))
+			return
+		}
+		defer f.Close()
+		WithUserAgents(f)(h)
+	}
+}
+
+// fail records the first error an option ran into, for Err.
+func (h *headers) fail(err error) {
+	if h.err == nil {
+		h.err = err
+	}
+}
+
+// Err returns the first error the options ran into, such as a
+// WithUserAgentFile file that couldn't be read, or nil. The generator
+// still works when there is one, without whatever the option would
+// have added.
+func (h *headers) Err() error {
+	return h.err
+}
+
+// lookup is LookupProfile with any user agents loaded by WithUserAgents
+// swapped in.
+func (h *headers) lookup(name string) (*Profile, bool) {
+	if p, ok := h.profiles[strings.ToLower(name)]; ok {
+		return p, true
+	}
+	return LookupProfile(name)
+}
+
+// loadProfiles makes the copies of the registered profiles that use the
+// user agents from WithUserAgents.
+func (h *headers) loadProfiles() {
+	if len(h.userAgents) == 0 {
+		return
+	}
+	h.profiles = map[string]*Profile{}
+	for name, uas := range h.userAgents {
+		p, ok := LookupProfile(name)
+		if !ok {
+			continue
+		}
+		cp := *p
+		cp.setUserAgents(uas)
+		if len(cp.UserAgents) > 0 {
+			h.profiles[name] = &cp
+		}
+	}
+}
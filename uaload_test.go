@@ -0,0 +1,162 @@
+package fuzzyHelpers
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseUserAgent(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		ua, browser, osys, version, chromium string
+	}{
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36", "chrome", "w", "120.0.0.0", ""},
+		{"Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Mobile Safari/537.36", "chrome", "a", "120.0.0.0", ""},
+		{"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36 Edg/120.0.2210.77", "edge", "m", "120.0.2210.77", "120.0.0.0"},
+		{"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/119.0.0.0 Safari/537.36 OPR/105.0.0.0", "opera", "l", "105.0.0.0", "119.0.0.0"},
+		{"Mozilla/5.0 (X11; Ubuntu; Linux x86_64; rv:121.0) Gecko/20100101 Firefox/121.0", "firefox", "l", "121.0", ""},
+		{"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.2 Safari/605.1.15", "safari", "m", "17.2", ""},
+	}
+	for _, tt := range tests {
+		browser, ua, ok := parseUserAgent(tt.ua)
+		if !ok || browser != tt.browser || ua.OS != tt.osys || ua.Version != tt.version || ua.Chromium != tt.chromium {
+			t.Errorf("%s: got %s %+v %v", tt.ua, browser, ua, ok)
+		}
+	}
+	for _, ua := range []string{
+		"curl/8.4.0",
+		"Mozilla/5.0 (iPhone; CPU iPhone OS 17_2 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) CriOS/120.0.6099.119 Mobile/15E148 Safari/604.1",
+		"Mozilla/5.0 (iPhone; CPU iPhone OS 17_2 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) FxiOS/121.0 Mobile/15E148 Safari/605.1.15",
+	} {
+		if browser, _, ok := parseUserAgent(ua); ok {
+			t.Errorf("%s: got %s, wanted no browser", ua, browser)
+		}
+	}
+}
+
+func TestReadUserAgents(t *testing.T) {
+	t.Parallel()
+	t.Run("plain text", func(t *testing.T) {
+		uas, err := ReadUserAgents(strings.NewReader(`
+# refreshed weekly
+Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36
+
+Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:121.0) Gecko/20100101 Firefox/121.0
+`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(uas["chrome"]) != 1 || len(uas["firefox"]) != 1 || len(uas) != 2 {
+			t.Errorf("got %+v", uas)
+		}
+	})
+	t.Run("json", func(t *testing.T) {
+		uas, err := ReadUserAgents(strings.NewReader(`[
+			{"browser": "Chrome", "os": "windows", "version": "120.0.6099.109", "ua": "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"},
+			{"browser": "brave", "ua": "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"},
+			{"ua": "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.2 Safari/605.1.15"}
+		]`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := UserAgent{OS: "w", Version: "120.0.6099.109", Value: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"}
+		if len(uas["chrome"]) != 1 || uas["chrome"][0] != want {
+			t.Errorf("got %+v want %+v", uas["chrome"], want)
+		}
+		if len(uas["brave"]) != 1 || uas["brave"][0].Version != "120.0.0.0" {
+			t.Errorf("got brave %+v", uas["brave"])
+		}
+		if len(uas["safari"]) != 1 || uas["safari"][0].OS != "m" {
+			t.Errorf("got safari %+v", uas["safari"])
+		}
+	})
+	for _, bad := range []string{
+		"",
+		"# nothing here",
+		"curl/8.4.0",
+		`[{"browser": "netscape", "os": "w", "version": "4.0", "ua": "Mozilla/4.0"}]`,
+		`[{"browser": "chrome", "os": "beos", "ua": "Mozilla/5.0 (X11; Linux x86_64) Chrome/120.0.0.0"}]`,
+		`[{"browser": "chrome"}]`,
+		`[{"ua": 1}]`,
+	} {
+		if uas, err := ReadUserAgents(strings.NewReader(bad)); err == nil {
+			t.Errorf("%q: got %+v, wanted an error", bad, uas)
+		}
+	}
+}
+
+func TestWithUserAgentFile(t *testing.T) {
+	t.Parallel()
+	const ua = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/121.0.0.0 Safari/537.36"
+	path := filepath.Join(t.TempDir(), "uas.json")
+	data := `[{"browser": "chrome", "os": "w", "version": "121.0.6167.85", "ua": "` + ua + `"}]`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	h := NewHeaders(WithProfile("chrome"), WithUserAgentFile(path))
+	for i := 0; i < 10; i++ {
+		headers, c := h.Generate()
+		if got := headers.Get("User-Agent"); got != ua {
+			t.Fatalf("got ua %s want %s", got, ua)
+		}
+		if got := headerValue(headers, "sec-ch-ua"); !strings.Contains(got, `"Google Chrome";v="121"`) {
+			t.Errorf("sec-ch-ua %s doesn't match the loaded ua", got)
+		}
+		if got := NewHeaders(WithChoice(c)).Headers().Get("User-Agent"); got != ua {
+			t.Errorf("replaying the choice got ua %s", got)
+		}
+	}
+	// other profiles keep their built in user agents.
+	if got := NewHeaders(WithProfile("firefox"), WithUserAgentFile(path)).Headers().Get("User-Agent"); !strings.Contains(got, "Firefox/") {
+		t.Errorf("got firefox ua %s", got)
+	}
+	// the loaded set only has windows uas, so others fall back to it.
+	if got := NewHeaders(WithProfile("chrome"), WithOS("m"), WithUserAgentFile(path)).Headers().Get("User-Agent"); got != ua {
+		t.Errorf("got ua %s on mac", got)
+	}
+	id := NewIdentity(WithProfile("chrome"), WithUserAgentFile(path))
+	if got := id.RequestHeaders(RequestFetch).Get("User-Agent"); got != ua {
+		t.Errorf("got identity ua %s", got)
+	}
+	if err := id.Err(); err != nil {
+		t.Errorf("got error %v loading a good file", err)
+	}
+	// the built in list is used when the file can't be read, and Err
+	// says why.
+	p, _ := LookupProfile("chrome")
+	missing := NewHeaders(WithProfile("chrome"), WithUserAgentFile(filepath.Join(t.TempDir(), "missing")))
+	if err := missing.Err(); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("got error %v for a missing file, want fs.ErrNotExist", err)
+	}
+	if err := NewIdentity(WithUserAgents(strings.NewReader("curl/8.4.0"))).Err(); err == nil {
+		t.Error("got no error for a list without browser uas")
+	}
+	got := missing.Headers().Get("User-Agent")
+	found := false
+	for _, u := range p.UserAgents {
+		found = found || u.Value == got
+	}
+	if !found {
+		t.Errorf("got ua %s, wanted a built in one", got)
+	}
+}
+
+func TestAcceptCHLoadedUserAgent(t *testing.T) {
+	t.Parallel()
+	const ua = "Mozilla/5.0 (Macintosh; Intel Mac OS X 13_5_1) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/121.0.0.0 Safari/537.36"
+	h := NewHeaders(WithProfile("chrome"), WithUserAgents(strings.NewReader(ua)))
+	req, err := http.NewRequest("GET", "https://example.com/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header = h.Headers()
+	sent := (&acceptCHTransport{}).addHints(req, []string{"sec-ch-ua-platform-version"})
+	if got := headerValue(sent.Header, "sec-ch-ua-platform-version"); got != `"13.5.1"` {
+		t.Errorf("got platform version %q", got)
+	}
+}
@@ -0,0 +1,165 @@
+package fuzzyHelpers
+
+import (
+	"sort"
+	"strings"
+)
+
+// Weights is how often each browser, os and browser version should be
+// picked, relative to the others. Headers uses DefaultWeights unless
+// given others with WithWeights.
+type Weights struct {
+	// Browsers weighs profiles by name. Profiles it doesn't list weigh
+	// 1, so give a profile 0 to never pick it at random.
+	Browsers map[string]float64
+	// OS weighs the operating systems WithOS("any") picks from, which
+	// are the ones listed here ("l", "m" and "w" if there are none).
+	OS map[string]float64
+	// Versions weighs user agents by how many major versions they're
+	// behind the newest one their profile has on the os: Versions[0]
+	// for the newest, Versions[1] for the one before and so on, with
+	// the last entry covering anything older. Empty means every
+	// version is as likely.
+	Versions []float64
+}
+
+// DefaultWeights returns rough worldwide browser and desktop os market
+// shares, with most users on the newest versions.
+func DefaultWeights() Weights {
+	return Weights{
+		Browsers: map[string]float64{
+			"chrome":  64,
+			"safari":  19,
+			"edge":    5,
+			"firefox": 3,
+			"opera":   2,
+			"brave":   1,
+		},
+		OS: map[string]float64{
+			"w": 72,
+			"m": 16,
+			"l": 4,
+		},
+		Versions: []float64{50, 25, 12, 6, 3, 2, 1},
+	}
+}
+
+// WithWeights replaces DefaultWeights with w. WithWeights(Weights{})
+// picks everything uniformly.
+func WithWeights(w Weights) optionHeaders {
+	return func(h *headers) {
+		h.weights = copyWeights(w)
+	}
+}
+
+func copyWeights(w Weights) Weights {
+	cp := Weights{
+		Browsers: map[string]float64{},
+		OS:       map[string]float64{},
+		Versions: append([]float64(nil), w.Versions...),
+	}
+	for name, v := range w.Browsers {
+		cp.Browsers[strings.ToLower(name)] = v
+	}
+	for osys, v := range w.OS {
+		cp.OS[strings.ToLower(osys)] = v
+	}
+	return cp
+}
+
+// browser returns the weight of the named profile.
+func (w Weights) browser(name string) float64 {
+	if v, ok := w.Browsers[name]; ok {
+		return v
+	}
+	return 1
+}
+
+// version returns the weight of a version rank behind the newest.
+func (w Weights) version(rank int) float64 {
+	if len(w.Versions) == 0 {
+		return 1
+	}
+	if rank >= len(w.Versions) {
+		rank = len(w.Versions) - 1
+	}
+	return w.Versions[rank]
+}
+
+// pick returns an index into weights, chosen in proportion to them.
+// Negative weights count as 0, and if nothing weighs anything every
+// index is as likely.
+func (h *headers) pick(weights []float64) int {
+	total := 0.0
+	for _, w := range weights {
+		if w > 0 {
+			total += w
+		}
+	}
+	if total == 0 {
+		return h.intn(len(weights))
+	}
+	r := h.float64() * total
+	for i, w := range weights {
+		if w <= 0 {
+			continue
+		}
+		if r < w {
+			return i
+		}
+		r -= w
+	}
+	// float rounding can leave r just short of total.
+	for i := len(weights) - 1; i >= 0; i-- {
+		if weights[i] > 0 {
+			return i
+		}
+	}
+	return 0
+}
+
+// randOS picks the os for WithOS("any").
+func (h *headers) randOS() string {
+	systems := make([]string, 0, len(h.weights.OS))
+	for osys := range h.weights.OS {
+		systems = append(systems, osys)
+	}
+	if len(systems) == 0 {
+		systems = []string{"l", "m", "w"}
+	}
+	sort.Strings(systems)
+	weights := make([]float64, len(systems))
+	for i, osys := range systems {
+		weights[i] = 1
+		if v, ok := h.weights.OS[osys]; ok {
+			weights[i] = v
+		}
+	}
+	return systems[h.pick(weights)]
+}
+
+// pickUserAgent picks one of p's user agents for osys, weighing them by
+// version.
+func (h *headers) pickUserAgent(p *Profile, osys string) UserAgent {
+	uas := p.byOS[p.uaOS(osys)]
+	if len(h.weights.Versions) == 0 {
+		return uas[h.intn(len(uas))]
+	}
+	// a major listed several times shares its weight, so duplicates
+	// don't skew the mix.
+	count := map[int]int{}
+	newest := 0
+	for _, ua := range uas {
+		m := major(ua.Version)
+		count[m]++
+		if m > newest {
+			newest = m
+		}
+	}
+	weights := make([]float64, len(uas))
+	for i, ua := range uas {
+		m := major(ua.Version)
+		weights[i] = h.weights.version(newest-m) / float64(count[m])
+	}
+	return uas[h.pick(weights)]
+}
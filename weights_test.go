@@ -0,0 +1,88 @@
+package fuzzyHelpers
+
+import (
+	"testing"
+)
+
+func TestDefaultWeights(t *testing.T) {
+	t.Parallel()
+	h := NewHeaders(WithSeed(7))
+	counts := map[string]int{}
+	newest := 0
+	const n = 2000
+	for i := 0; i < n; i++ {
+		_, c := h.Generate()
+		counts[c.Profile]++
+		if c.Profile == "chrome" && major(c.UserAgent.Version) == 112 {
+			newest++
+		}
+	}
+	if counts["chrome"] < n/2 {
+		t.Errorf("chrome was picked %d times out of %d, wanted most of them", counts["chrome"], n)
+	}
+	if counts["firefox"] == 0 || counts["firefox"] > counts["edge"] {
+		t.Errorf("firefox was picked %d times, edge %d", counts["firefox"], counts["edge"])
+	}
+	if counts["safari"] != 0 {
+		t.Errorf("safari was picked on windows")
+	}
+	// chrome's newest windows ua is 112, which should be the most common.
+	if newest < counts["chrome"]/3 {
+		t.Errorf("chrome 112 was picked %d times out of %d", newest, counts["chrome"])
+	}
+}
+
+func TestWithWeights(t *testing.T) {
+	t.Parallel()
+	h := NewHeaders(
+		WithSeed(1),
+		WithOS("any"),
+		WithWeights(Weights{
+			Browsers: map[string]float64{"Firefox": 1, "chrome": 0, "edge": 0, "opera": 0, "brave": 0, "safari": 0},
+			OS:       map[string]float64{"A": 1},
+			Versions: []float64{1, 0},
+		}),
+	)
+	if h.osys != "a" {
+		t.Fatalf("got os %s want a", h.osys)
+	}
+	for i := 0; i < 50; i++ {
+		_, c := h.Generate()
+		if c.Profile != "firefox" || c.UserAgent.Version != "112.0" {
+			t.Fatalf("got %s %s, wanted firefox 112.0 only", c.Profile, c.UserAgent.Version)
+		}
+	}
+
+	// nothing weighed: uniform, as before weights existed.
+	h = NewHeaders(WithSeed(1), WithWeights(Weights{}))
+	counts := map[string]int{}
+	for i := 0; i < 1000; i++ {
+		_, c := h.Generate()
+		counts[c.Profile]++
+	}
+	for name, n := range counts {
+		if n < 120 {
+			t.Errorf("%s was picked %d times out of 1000: %v", name, n, counts)
+		}
+	}
+}
+
+func TestPick(t *testing.T) {
+	t.Parallel()
+	h := NewHeaders(WithSeed(3))
+	got := make([]int, 4)
+	for i := 0; i < 4000; i++ {
+		got[h.pick([]float64{3, 0, -1, 1})]++
+	}
+	if got[1] != 0 || got[2] != 0 {
+		t.Errorf("picked unweighted entries: %v", got)
+	}
+	if got[0] < 2700 || got[0] > 3300 {
+		t.Errorf("got %v, wanted about 3000 of index 0", got)
+	}
+	for i := 0; i < 100; i++ {
+		if n := h.pick([]float64{0, 0}); n < 0 || n > 1 {
+			t.Fatalf("got index %d", n)
+		}
+	}
+}